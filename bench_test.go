@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+type benchDocument struct {
+	name string
+	data []byte
+}
+
+// benchDocuments are generated documents with different characteristics.
+// They are deterministic, so that results are comparable between runs.
+var benchDocuments = []benchDocument{
+	{"nested", benchNested(500)},
+	{"wide", benchWide(50000)},
+	{"strings", benchStrings(10000)},
+	{"numbers", benchNumbers(20000)},
+	{"twitter", benchTwitter(200)},
+	{"citm", benchCITM(500)},
+}
+
+// benchNested returns arrays and objects nested alternately.
+func benchNested(depth int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i != depth; i++ {
+		if i%2 == 0 {
+			fmt.Fprintf(&buf, `[%d, `, i)
+		} else {
+			fmt.Fprintf(&buf, `{"level": %d, "next": `, i)
+		}
+	}
+	buf.WriteString(`null`)
+	for i := depth - 1; i >= 0; i-- {
+		if i%2 == 0 {
+			buf.WriteString(`]`)
+		} else {
+			buf.WriteString(`}`)
+		}
+	}
+	return buf.Bytes()
+}
+
+// benchWide returns a flat array of small values.
+func benchWide(count int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`[`)
+	for i := 0; i != count; i++ {
+		if i != 0 {
+			buf.WriteString(`,`)
+		}
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&buf, `%d`, i)
+		case 1:
+			buf.WriteString(`true`)
+		case 2:
+			buf.WriteString(`null`)
+		case 3:
+			buf.WriteString(`{}`)
+		}
+	}
+	buf.WriteString(`]`)
+	return buf.Bytes()
+}
+
+// benchStrings returns an array of strings, some of them with escapes.
+func benchStrings(count int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`[`)
+	for i := 0; i != count; i++ {
+		if i != 0 {
+			buf.WriteString(`, `)
+		}
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&buf, `"plain string number %d with some more text"`, i)
+		case 1:
+			fmt.Fprintf(&buf, `"escaped \"%d\"\n\tpath C:\\dir\\file \u00e4\u00f6\u00fc \ud83d\ude00"`, i)
+		case 2:
+			fmt.Fprintf(&buf, `"unicode äöü 日本語 %d"`, i)
+		}
+	}
+	buf.WriteString(`]`)
+	return buf.Bytes()
+}
+
+// benchNumbers returns an array of integer and floating point numbers.
+func benchNumbers(count int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`[`)
+	x := uint32(1)
+	for i := 0; i != count; i++ {
+		if i != 0 {
+			buf.WriteString(`,`)
+		}
+		// simple linear congruential generator
+		x = x*1664525 + 1013904223
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&buf, `%d`, int32(x))
+		case 1:
+			fmt.Fprintf(&buf, `%.6f`, float64(x)/1e6)
+		case 2:
+			fmt.Fprintf(&buf, `%.3e`, float64(x)*1e10)
+		}
+	}
+	buf.WriteString(`]`)
+	return buf.Bytes()
+}
+
+// benchTwitter returns a document with the structure of a search result of
+// the Twitter API, with many string-valued members and nested objects.
+func benchTwitter(count int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"statuses": [`)
+	for i := 0; i != count; i++ {
+		if i != 0 {
+			buf.WriteString(`,`)
+		}
+		fmt.Fprintf(&buf, `
+  {
+    "metadata": {"result_type": "recent", "iso_language_code": "en"},
+    "created_at": "Sun Aug 31 00:%02d:15 +0000 2014",
+    "id": %d,
+    "id_str": "%d",
+    "text": "@user%d status text number %d with a link http://t.co/%d and #hashtag \u2026",
+    "source": "<a href=\"http://example.com\" rel=\"nofollow\">client</a>",
+    "truncated": false,
+    "in_reply_to_status_id": null,
+    "in_reply_to_screen_name": "user%d",
+    "user": {
+      "id": %d,
+      "name": "User %d",
+      "screen_name": "user%d",
+      "location": "",
+      "description": "Description of user %d \ud83d\ude00",
+      "url": null,
+      "entities": {"description": {"urls": []}},
+      "protected": false,
+      "followers_count": %d,
+      "friends_count": %d,
+      "created_at": "Sun Aug 31 00:00:00 +0000 2014",
+      "favourites_count": 0,
+      "utc_offset": null,
+      "time_zone": null,
+      "geo_enabled": false,
+      "verified": false,
+      "lang": "en",
+      "profile_background_color": "C0DEED",
+      "profile_image_url": "http://pbs.twimg.com/profile_images/%d/normal.jpeg",
+      "default_profile": true
+    },
+    "geo": null,
+    "coordinates": null,
+    "retweet_count": %d,
+    "favorite_count": 0,
+    "entities": {
+      "hashtags": [{"text": "hashtag", "indices": [40, 48]}],
+      "symbols": [],
+      "urls": [{"url": "http://t.co/%d", "expanded_url": "http://example.com/%d", "indices": [20, 39]}],
+      "user_mentions": [{"screen_name": "user%d", "name": "User %d", "id": %d, "id_str": "%d", "indices": [0, 8]}]
+    },
+    "favorited": false,
+    "retweeted": false,
+    "lang": "en"
+  }`, i%60, 505874924095815681+i, 505874924095815681+i, i, i, i, i,
+			1000+i, i, i, i, i*17, i*3, 1000+i, i%10, i, i, i, i, 1000+i, 1000+i)
+	}
+	buf.WriteString(`],
+  "search_metadata": {"completed_in": 0.087, "max_id": 505874924095815700, "query": "%E4%B8%80", "count": 100}
+}`)
+	return buf.Bytes()
+}
+
+// benchCITM returns a document with the structure of the CITM catalog, with
+// many integer-valued members and arrays of small objects.
+func benchCITM(count int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"areaNames": {`)
+	for i := 0; i != 20; i++ {
+		if i != 0 {
+			buf.WriteString(`,`)
+		}
+		fmt.Fprintf(&buf, `"%d": "Area %d"`, 205705993+i, i)
+	}
+	buf.WriteString(`}, "events": {`)
+	for i := 0; i != count; i++ {
+		if i != 0 {
+			buf.WriteString(`,`)
+		}
+		fmt.Fprintf(&buf, `"%d": {"description": null, "id": %d, "logo": "/images/UE0AAAAACEKo%dQAAAAVDSVRN", "name": "Event %d", "subTopicIds": [337184284, 337184263, %d], "subjectCode": null, "subtitle": null, "topicIds": [324846099, %d]}`,
+			138586341+i, 138586341+i, i, i, 337184280+i, 107888604+i)
+	}
+	buf.WriteString(`}, "performances": [`)
+	for i := 0; i != count; i++ {
+		if i != 0 {
+			buf.WriteString(`,`)
+		}
+		fmt.Fprintf(&buf, `{"eventId": %d, "id": %d, "logo": null, "name": null, "prices": [{"amount": %d, "audienceSubCategoryId": 337100890, "seatCategoryId": %d}, {"amount": %d, "audienceSubCategoryId": 337100890, "seatCategoryId": %d}], "seatCategories": [{"areas": [{"areaId": 205705999, "blockIds": []}, {"areaId": 205705998, "blockIds": []}], "seatCategoryId": %d}], "seatMapImage": null, "start": %d, "venueCode": "PLEYEL_PLEYEL"}`,
+			138586341+i, 339887544+i, 90250+i*100, 338937295+i, 66500+i*100, 338937296+i, 338937295+i, 1372701600000+int64(i)*86400000)
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+// runBenchmark runs fn for every benchmark document, reporting the throughput
+// relative to the document size and the allocations.
+func runBenchmark(b *testing.B, fn func(b *testing.B, doc benchDocument)) {
+	for _, doc := range benchDocuments {
+		b.Run(doc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(doc.data)))
+			b.ReportAllocs()
+			fn(b, doc)
+		})
+	}
+}
+
+// mustParseDocument parses a benchmark document or fails the benchmark.
+func mustParseDocument(b *testing.B, data []byte) *Document {
+	b.Helper()
+	doc, err := ParseDocument(data)
+	if err != nil {
+		b.Fatal("failed to parse document", err)
+	}
+	return doc
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		emit := func(JSONElement) bool { return true }
+		for i := 0; i < b.N; i++ {
+			if err := tokenize(doc.data, ParseOptions{}, emit); err != nil {
+				b.Fatal("unexpected failure", err)
+			}
+		}
+	})
+}
+
+func BenchmarkParseJSON(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		for i := 0; i < b.N; i++ {
+			if _, err := parseJSON(doc.data); err != nil {
+				b.Fatal("unexpected failure", err)
+			}
+		}
+	})
+}
+
+// BenchmarkParseJSONParallel parses a large document with different numbers
+// of goroutines tokenizing it, compared to parsing it sequentially.
+func BenchmarkParseJSONParallel(b *testing.B) {
+	data := benchTwitter(20000)
+	parallelisms := []int{0, 2, 4}
+	if cpus := runtime.NumCPU(); cpus > 4 {
+		parallelisms = append(parallelisms, cpus)
+	}
+	for _, parallelism := range parallelisms {
+		name := fmt.Sprint(parallelism)
+		if parallelism == 0 {
+			name = "sequential"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := parseJSONOptions(data, ParseOptions{Parallelism: parallelism}); err != nil {
+					b.Fatal("unexpected failure", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseDocument(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		for i := 0; i < b.N; i++ {
+			mustParseDocument(b, doc.data)
+		}
+	})
+}
+
+func BenchmarkEqualDocuments(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		first := mustParseDocument(b, doc.data)
+		second := mustParseDocument(b, doc.data)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if !EqualDocuments(first, second) {
+				b.Fatal("documents differ")
+			}
+		}
+	})
+}
+
+func BenchmarkValidateDocument(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		parsed := mustParseDocument(b, doc.data)
+		schema, err := CompileSchema(InferSchema(parsed))
+		if err != nil {
+			b.Fatal("failed to compile schema", err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := schema.ValidateDocument(parsed); err != nil {
+				b.Fatal("unexpected failure", err)
+			}
+		}
+	})
+}
+
+func BenchmarkInferSchema(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		parsed := mustParseDocument(b, doc.data)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			InferSchema(parsed)
+		}
+	})
+}
+
+func BenchmarkGenerateGo(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		parsed := mustParseDocument(b, doc.data)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := GenerateGo("bench", "Document", parsed); err != nil {
+				b.Fatal("unexpected failure", err)
+			}
+		}
+	})
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		for i := 0; i < b.N; i++ {
+			var v interface{}
+			if err := Unmarshal(doc.data, &v); err != nil {
+				b.Fatal("unexpected failure", err)
+			}
+		}
+	})
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	runBenchmark(b, func(b *testing.B, doc benchDocument) {
+		var v interface{}
+		if err := Unmarshal(doc.data, &v); err != nil {
+			b.Fatal("unexpected failure", err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := Marshal(v); err != nil {
+				b.Fatal("unexpected failure", err)
+			}
+		}
+	})
+}
+
+func TestBenchDocuments(t *testing.T) {
+	// the generated documents must be valid, otherwise the benchmarks measure
+	// error handling
+	for _, doc := range benchDocuments {
+		if _, err := parseJSON(doc.data); err != nil {
+			t.Errorf("%s: %v", doc.name, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// compatCorpus is the base corpus for the comparison with encoding/json. It
+// is extended by compatInputs with systematic mutations of these inputs.
+var compatCorpus = []string{
+	// literals
+	`null`, `true`, `false`, `nul`, `nulll`, `True`, `fals`, `truefalse`,
+	// numbers
+	`0`, `-0`, `1`, `-1`, `12`, `0.5`, `-0.5`, `1.25e3`, `1E+3`, `1e-3`, `0e0`,
+	`123456789012345678901234567890`, `1e400`, `-1e-400`, `01`, `-01`, `00`,
+	`1.`, `.5`, `-`, `+1`, `1e`, `1e+`, `1.e1`, `0x10`, `1.2.3`, `--1`, `1-`,
+	`NaN`, `Infinity`,
+	// strings
+	`""`, `"a"`, `"\""`, `"\\"`, `"\/"`, `"\b\f\n\r\t"`, `"A"`, `"ä"`,
+	`"😀"`, `"\ud800"`, `"\udc00\ud800"`, `"ä😀"`, `"\x"`, `"\u12"`,
+	`"\u12G4"`, `"a`, `'a'`, "\"\t\"", "\"\x7f\"", "\"\x00\"",
+	// arrays
+	`[]`, `[1]`, `[1,2]`, `[ 1 , 2 ]`, `[[]]`, `[[],[[]]]`, `[1,]`, `[,1]`, `[,]`,
+	`[1 2]`, `[`, `]`, `[1`, `[1,`, `[[]`, `[]]`, `[1:2]`, `["a":1]`,
+	// objects
+	`{}`, `{"a":1}`, `{"a":1,"b":[true,null]}`, `{ "a" : { "b" : { } } }`,
+	`{"a":1,"a":2}`, `{"a"}`, `{"a":}`, `{"a" 1}`, `{:1}`, `{1:1}`, `{a:1}`,
+	`{"a":1,}`, `{,"a":1}`, `{"a":1 "b":2}`, `{"a"::1}`, `{`, `}`, `{"a":1`,
+	`{"a":1}}`, `{"a":[}`, `{"a":{]}`,
+	// whitespace and multiple values
+	" \t\r\n1 \t\r\n", "\f1", "\v1", `1 2`, `[] []`, `{}{}`, `1,2`, `"a" "b"`,
+	`[1]x`, `nullx`,
+	// documents
+	`{"id": 1, "name": "x", "tags": ["a", "b"], "nested": {"deep": [[1, {"k": -2.5e-3}]]}, "ok": false, "none": null}`,
+	`[{"a": "ä\n"}, 0, -0.0, 1e2, [], {}, "", true]`,
+}
+
+// compatInputs returns the corpus with systematic mutations: all prefixes,
+// all inputs with one byte removed and all inputs with one byte replaced.
+func compatInputs() [][]byte {
+	replacements := []byte(`{}[],:" 0-.eE\a`)
+	seen := map[string]bool{}
+	res := [][]byte{}
+	add := func(b []byte) {
+		if !seen[string(b)] {
+			seen[string(b)] = true
+			res = append(res, b)
+		}
+	}
+
+	for _, s := range compatCorpus {
+		add([]byte(s))
+		for i := 0; i < len(s); i++ {
+			add([]byte(s[:i]))
+			add([]byte(s[:i] + s[i+1:]))
+			for _, r := range replacements {
+				add([]byte(s[:i] + string(r) + s[i+1:]))
+			}
+		}
+	}
+	return res
+}
+
+// compatOptions are the parser options that match the behavior of
+// encoding/json, which replaces invalid UTF-8 and unpaired surrogates.
+var compatOptions = ParseOptions{ReplaceInvalidUTF8: true, AllowLoneSurrogates: true}
+
+// isCompatDeviation tells whether the input is subject to a known and
+// intentional deviation from encoding/json: parseJSON accepts empty input
+// (or input consisting only of whitespace) as a document without value.
+func isCompatDeviation(data []byte) bool {
+	return len(bytes.Trim(data, " \t\r\n")) == 0
+}
+
+// TestCompatValid compares the accept/reject decision of parseJSON with
+// encoding/json.Valid.
+func TestCompatValid(t *testing.T) {
+	for _, data := range compatInputs() {
+		if isCompatDeviation(data) {
+			continue
+		}
+		_, err := parseJSONOptions(data, compatOptions)
+		expected := json.Valid(data)
+		if expected && err != nil {
+			t.Errorf("%q: rejected valid input: %v", data, err)
+		}
+		if !expected && err == nil {
+			t.Errorf("%q: accepted invalid input", data)
+		}
+	}
+}
+
+// TestCompatUnmarshal compares the values decoded by Unmarshal with those of
+// encoding/json.Unmarshal, both for an empty interface and for typed values.
+func TestCompatUnmarshal(t *testing.T) {
+	targets := map[string]func() interface{}{
+		"interface": func() interface{} { return new(interface{}) },
+		"float":     func() interface{} { return new(float64) },
+		"int":       func() interface{} { return new(int) },
+		"string":    func() interface{} { return new(string) },
+		"slice":     func() interface{} { return new([]interface{}) },
+		"map":       func() interface{} { return new(map[string]interface{}) },
+		"number":    func() interface{} { return new(json.Number) },
+	}
+
+	for _, data := range compatInputs() {
+		if isCompatDeviation(data) || !json.Valid(data) {
+			continue
+		}
+		doc, err := ParseDocumentOptions(data, compatOptions)
+		if err != nil {
+			t.Errorf("%q: unexpected failure %v", data, err)
+			continue
+		}
+		for name, target := range targets {
+			expected := target()
+			expectedErr := json.Unmarshal(data, expected)
+			received := target()
+			receivedErr := UnmarshalDocument(doc, received)
+
+			if (expectedErr == nil) != (receivedErr == nil) {
+				t.Errorf("%q into %s: expected error %v, received %v", data, name, expectedErr, receivedErr)
+				continue
+			}
+			if expectedErr != nil {
+				// the value after errors differs between Go versions
+				continue
+			}
+			if !reflect.DeepEqual(expected, received) {
+				t.Errorf("%q into %s: expected %#v, received %#v", data, name,
+					reflect.ValueOf(expected).Elem().Interface(),
+					reflect.ValueOf(received).Elem().Interface())
+			}
+		}
+	}
+}
+
+// TestCompatMarshal checks that decoding and re-encoding the corpus gives the
+// same result as encoding/json.
+func TestCompatMarshal(t *testing.T) {
+	for _, data := range compatInputs() {
+		if isCompatDeviation(data) || !json.Valid(data) {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			// out of range numbers
+			continue
+		}
+
+		expected, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal("failed to marshal", err)
+		}
+		// HTML characters and line separators are not escaped, see Marshal
+		for _, r := range []string{"&", "<", ">", "\u2028", "\u2029"} {
+			escaped := []byte(strconv.QuoteToASCII(r))
+			expected = bytes.ReplaceAll(expected, escaped[1:len(escaped)-1], []byte(r))
+		}
+
+		received, err := Marshal(value)
+		if err != nil {
+			t.Errorf("%q: unexpected failure %v", data, err)
+			continue
+		}
+		if !bytes.Equal(expected, received) {
+			t.Errorf("%q: expected %s, received %s", data, expected, received)
+		}
+	}
+}
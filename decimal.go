@@ -0,0 +1,168 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// maxRatExponent limits the exponent of numbers converted to rationals, since
+// the memory required for that grows with the exponent and not with the size
+// of the input token.
+const maxRatExponent = 10000
+
+// decimal is an arbitrary-precision decimal number with the value
+// "0.digits * 10^exp". The digits have neither leading nor trailing zeros, so
+// zero is represented by empty digits.
+type decimal struct {
+	neg    bool
+	digits string
+	exp    *big.Int
+}
+
+// parseDecimal converts a number token into a decimal. Since the exponent is
+// computed with arbitrary precision, this doesn't lose any information, not
+// even for huge exponents.
+func parseDecimal(token []byte) (decimal, bool) {
+	s := string(token)
+	res := decimal{exp: new(big.Int)}
+	if strings.HasPrefix(s, "-") {
+		res.neg = true
+		s = s[1:]
+	}
+
+	// split off exponent
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e := strings.TrimPrefix(s[i+1:], "+")
+		if _, ok := res.exp.SetString(e, 10); !ok {
+			return decimal{}, false
+		}
+		s = s[:i]
+	}
+
+	// combine integer and fraction digits, the fraction shifts the exponent
+	digits := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits = s[:i] + s[i+1:]
+		res.exp.Sub(res.exp, big.NewInt(int64(len(s)-i-1)))
+	}
+	if digits == "" {
+		return decimal{}, false
+	}
+
+	// the value is now "digits * 10^exp", normalize to "0.digits * 10^exp"
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		// zero has no sign and no exponent
+		return decimal{exp: new(big.Int)}, true
+	}
+	res.exp.Add(res.exp, big.NewInt(int64(len(digits))))
+	res.digits = strings.TrimRight(digits, "0")
+	return res, true
+}
+
+// String returns the canonical representation "[-]0.DIGITSeEXP" or "0".
+func (x decimal) String() string {
+	if x.digits == "" {
+		return "0"
+	}
+	res := "0." + x.digits + "e" + x.exp.String()
+	if x.neg {
+		res = "-" + res
+	}
+	return res
+}
+
+// sign returns -1, 0 or +1 depending on the sign of the number.
+func (x decimal) sign() int {
+	switch {
+	case x.digits == "":
+		return 0
+	case x.neg:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// isInteger tells whether the number doesn't have a fractional part.
+func (x decimal) isInteger() bool {
+	return x.digits == "" || x.exp.Cmp(big.NewInt(int64(len(x.digits)))) >= 0
+}
+
+// rat converts the number to a rational. This fails if the exponent is too
+// large, see maxRatExponent.
+func (x decimal) rat() (*big.Rat, bool) {
+	res := new(big.Rat)
+	if x.digits == "" {
+		return res, true
+	}
+	if x.exp.CmpAbs(big.NewInt(maxRatExponent)) > 0 {
+		return nil, false
+	}
+	mantissa, _ := new(big.Int).SetString(x.digits, 10)
+	shift := x.exp.Int64() - int64(len(x.digits))
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(abs64(shift)), nil)
+	if shift >= 0 {
+		res.SetInt(mantissa.Mul(mantissa, scale))
+	} else {
+		res.SetFrac(mantissa, scale)
+	}
+	if x.neg {
+		res.Neg(res)
+	}
+	return res, true
+}
+
+// plain returns the number in plain decimal notation. For huge exponents,
+// this falls back to the canonical representation.
+func (x decimal) plain() string {
+	r, ok := x.rat()
+	if !ok {
+		return x.String()
+	}
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return r.FloatString(len(x.digits) - int(x.exp.Int64()))
+}
+
+// compareDecimal returns -1, 0 or +1 if a is less than, equal to or greater
+// than b.
+func compareDecimal(a, b decimal) int {
+	sa, sb := a.sign(), b.sign()
+	if sa != sb {
+		if sa < sb {
+			return -1
+		}
+		return 1
+	}
+	if sa == 0 {
+		return 0
+	}
+
+	// compare magnitude, first by exponent, then by digits
+	res := a.exp.Cmp(b.exp)
+	if res == 0 {
+		res = strings.Compare(a.digits, b.digits)
+	}
+	return res * sa
+}
+
+// canonicalNumber converts a number token into a canonical representation,
+// so that two tokens denote the same value exactly if their canonical
+// representations are equal. Tokens that aren't decimal numbers, like the
+// Infinity and NaN of JSON5, are returned unchanged.
+func canonicalNumber(token []byte) string {
+	x, ok := parseDecimal(token)
+	if !ok {
+		return string(token)
+	}
+	return x.String()
+}
+
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Diagnostic is an error in an input, located for humans.
+type Diagnostic struct {
+	Offset   int    // offset of the error within the input data
+	Line     int    // line of the error, counted from 1
+	Col      int    // column of the error in runes, counted from 1
+	Err      error  // underlying error, e.g. ErrInvalidToken
+	Expected string // tokens expected at the error, empty if unknown
+	Source   string // line of the input containing the error
+}
+
+// Diagnose parses the data configured by the options and returns all errors
+// that it contains, like ParseDocumentRecover. Lines and columns refer to the
+// decoded input, so they are the same for all encodings.
+func Diagnose(data []byte, opts ParseOptions) []Diagnostic {
+	in, opts, err := prepareInput(data, opts)
+	if err != nil {
+		var e *ParseError
+		if !errors.As(err, &e) {
+			e = &ParseError{Offset: 0, Err: err}
+		}
+		return []Diagnostic{newDiagnostic(NewLineIndex(data, ColumnRunes), e.Offset, e.Err)}
+	}
+	p, _ := recoverTree(in.text, opts)
+	lines := NewLineIndex(in.text, ColumnRunes)
+	res := []Diagnostic{}
+	for i, e := range p.errs {
+		d := newDiagnostic(lines, e.Offset, e.Err)
+		d.Offset = in.originalOffset(e.Offset)
+		if errors.Is(e.Err, ErrInvalidStructure) || errors.Is(e.Err, ErrUnexpectedEOF) {
+			// the expected tokens only matter for errors of the structure,
+			// not for invalid tokens
+			d.Expected = p.hints[i]
+		}
+		res = append(res, d)
+	}
+	return res
+}
+
+// newDiagnostic returns the diagnostic for an error at the given offset.
+func newDiagnostic(lines *LineIndex, offset int, err error) Diagnostic {
+	line, col := lines.Position(offset)
+	source := bytes.TrimSuffix(lines.Line(line), []byte{'\r'})
+	return Diagnostic{Offset: offset, Line: line, Col: col, Err: err, Source: string(source)}
+}
+
+// ANSI escape sequences used by writeDiagnostic
+const (
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[1;31m"
+	ansiGreen = "\x1b[1;32m"
+	ansiReset = "\x1b[0m"
+)
+
+// writeDiagnostic writes the diagnostic in the style of compilers: the file
+// name, line and column, the error, the offending source line and a caret
+// under the error column.
+func writeDiagnostic(w io.Writer, file string, d Diagnostic, color bool) error {
+	style := func(s, code string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	msg := d.Err.Error()
+	if d.Expected != "" {
+		msg += ", expected " + d.Expected
+	}
+	// keep tabs, so that the caret lines up
+	var indent strings.Builder
+	for i, r := range []rune(d.Source) {
+		if i >= d.Col-1 {
+			break
+		}
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s %s %s\n%s\n%s%s\n",
+		style(fmt.Sprintf("%s:%d:%d:", file, d.Line, d.Col), ansiBold),
+		style("error:", ansiRed), msg,
+		d.Source,
+		indent.String(), style("^", ansiGreen))
+	return err
+}
+
+// isTerminal tells whether the file is a terminal and colors are not disabled
+// by the NO_COLOR environment variable.
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	cases := map[string]struct {
+		data     string
+		opts     ParseOptions
+		line     int
+		col      int
+		err      error
+		expected string
+		source   string
+	}{
+		"invalid token":     {data: "{\n  \"a\": tru\n}", line: 2, col: 8, err: ErrInvalidToken, source: `  "a": tru`},
+		"invalid utf8":      {data: "[\"a\xffb\"]", line: 1, col: 4, err: ErrInvalidUTF8, source: "[\"a\xffb\"]"},
+		"missing colon":     {data: `{"a" 1}`, line: 1, col: 6, err: ErrInvalidStructure, expected: "':'", source: `{"a" 1}`},
+		"missing comma":     {data: "[1\r\n 2]", line: 2, col: 2, err: ErrInvalidStructure, expected: "',' or ']'", source: " 2]"},
+		"trailing comma":    {data: `{"a": 1,}`, line: 1, col: 8, err: ErrInvalidStructure, expected: "a member name", source: `{"a": 1,}`},
+		"trailing in jsonc": {data: `[1,, 2]`, opts: ParseOptions{Syntax: SyntaxJSONC}, line: 1, col: 4, err: ErrInvalidStructure, expected: "a value or ']'", source: `[1,, 2]`},
+		"second value":      {data: `"ä" 2`, line: 1, col: 5, err: ErrInvalidStructure, expected: "end of input", source: `"ä" 2`},
+		"unexpected end":    {data: "[\n  {\"a\": 1", line: 2, col: 10, err: ErrUnexpectedEOF, expected: "',' or '}'", source: `  {"a": 1`},
+		"duplicate key":     {data: `{"a": 1, "a": 2}`, opts: ParseOptions{DuplicateKeys: DuplicateKeysReject}, line: 1, col: 10, err: ErrDuplicateKey, source: `{"a": 1, "a": 2}`},
+		"byte order mark":   {data: "\ufeff1", opts: ParseOptions{RejectBOM: true}, line: 1, col: 1, err: ErrByteOrderMark, source: "\ufeff1"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			diagnostics := Diagnose([]byte(c.data), c.opts)
+			if len(diagnostics) == 0 {
+				t.Fatal("expected a diagnostic")
+			}
+			d := diagnostics[0]
+			if d.Line != c.line || d.Col != c.col {
+				t.Errorf("expected position %d:%d, received %d:%d", c.line, c.col, d.Line, d.Col)
+			}
+			if !errors.Is(d.Err, c.err) {
+				t.Errorf("expected error %v, received %v", c.err, d.Err)
+			}
+			if d.Expected != c.expected {
+				t.Errorf("expected hint %q, received %q", c.expected, d.Expected)
+			}
+			if d.Source != c.source {
+				t.Errorf("expected source %q, received %q", c.source, d.Source)
+			}
+		})
+	}
+
+	if diagnostics := Diagnose([]byte(`{"a": [1, 2]}`), ParseOptions{}); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics for valid input, received %v", diagnostics)
+	}
+	if diagnostics := Diagnose(utf16LE("[1\n 2]"), ParseOptions{}); len(diagnostics) != 1 || diagnostics[0].Offset != 8 || diagnostics[0].Col != 2 {
+		t.Errorf("expected diagnostic at offset 8 and column 2, received %v", diagnostics)
+	}
+}
+
+func TestWriteDiagnostic(t *testing.T) {
+	d := Diagnose([]byte("{\n\t\"a\": tru\n}"), ParseOptions{})[0]
+
+	var b strings.Builder
+	if err := writeDiagnostic(&b, "in.json", d, false); err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	expected := "in.json:2:7: error: invalid token\n\t\"a\": tru\n\t     ^\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, received %q", expected, b.String())
+	}
+
+	b.Reset()
+	writeDiagnostic(&b, "in.json", d, true)
+	expected = "\x1b[1min.json:2:7:\x1b[0m \x1b[1;31merror:\x1b[0m invalid token\n\t\"a\": tru\n\t     \x1b[1;32m^\x1b[0m\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, received %q", expected, b.String())
+	}
+
+	// errors of the structure come with the expected tokens
+	b.Reset()
+	writeDiagnostic(&b, "in.json", Diagnose([]byte(`[1 2]`), ParseOptions{})[0], false)
+	expected = "in.json:1:4: error: invalid structure, expected ',' or ']'\n[1 2]\n   ^\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, received %q", expected, b.String())
+	}
+}
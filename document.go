@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Document is a parsed JSON document, i.e. the input data together with the
+// syntax tree that was parsed from it.
+type Document struct {
+	data     []byte
+	elements []JSONElement
+	syntax   Syntax
+	ends     []int // index of the terminating element of every aggregate value
+}
+
+// newDocument returns the document of the given syntax tree. It indexes the
+// ends of the arrays and objects, so that traversing the tree takes linear
+// time even for deeply nested values.
+func newDocument(data []byte, elements []JSONElement, syntax Syntax) *Document {
+	ends := make([]int, len(elements))
+	open := []int{}
+	for i, e := range elements {
+		switch e.tpe {
+		case tObjectStart, tArrayStart:
+			open = append(open, i)
+		case tObjectEnd, tArrayEnd:
+			if len(open) > 0 {
+				ends[open[len(open)-1]] = i
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	for _, i := range open {
+		ends[i] = len(elements) - 1
+	}
+	return &Document{data: data, elements: elements, syntax: syntax, ends: ends}
+}
+
+// ParseDocument parses the given data into a document.
+func ParseDocument(data []byte) (*Document, error) {
+	return ParseDocumentOptions(data, ParseOptions{})
+}
+
+// ParseDocumentOptions parses the given data into a document, with the parser
+// configured by the given options.
+//
+// Input in UTF-16 or UTF-32 is detected and converted to UTF-8 and a leading
+// byte order mark is skipped. Offsets in errors refer to the original data.
+func ParseDocumentOptions(data []byte, opts ParseOptions) (*Document, error) {
+	in, opts, err := prepareInput(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := parseJSONOptions(in.text, opts)
+	if err != nil {
+		return nil, in.mapError(err)
+	}
+	return newDocument(in.text, elements, opts.Syntax), nil
+}
+
+// isValue tells whether the token type is the (start of a) value, as opposed
+// to separators and closing tokens.
+func isValue(tpe int) bool {
+	switch tpe {
+	case tObjectStart, tArrayStart, tString, tNull, tNumber, tBool:
+		return true
+	default:
+		return false
+	}
+}
+
+// root returns the index of the root value or zero if the document is empty.
+func (d *Document) root() int {
+	for i := 1; i < len(d.elements); i++ {
+		if isValue(d.elements[i].tpe) {
+			return i
+		}
+	}
+	return 0
+}
+
+// children returns the indices of the values contained in the aggregate value
+// at the given index, or the values of the document for index zero. For
+// objects, keys and values alternate.
+func (d *Document) children(index int) []int {
+	res := []int{}
+	end := len(d.elements)
+	if index > 0 {
+		end = d.end(index)
+	}
+	for i := index + 1; i < end; i++ {
+		if isValue(d.elements[i].tpe) {
+			res = append(res, i)
+			// skip the contents of nested arrays and objects
+			i = d.end(i)
+		}
+	}
+	return res
+}
+
+// members returns the values of the object at the given index, keyed by the
+// unescaped member names. For duplicate names, the last member wins.
+func (d *Document) members(index int) (map[string]int, error) {
+	children := d.children(index)
+	res := make(map[string]int, len(children)/2)
+	for i := 0; i+1 < len(children); i += 2 {
+		key, err := d.stringValue(children[i])
+		if err != nil {
+			return nil, err
+		}
+		res[key] = children[i+1]
+	}
+	return res, nil
+}
+
+// end returns the index of the element that terminates the value at the
+// given index. For scalar values, this is the index itself.
+func (d *Document) end(index int) int {
+	switch d.elements[index].tpe {
+	case tObjectStart, tArrayStart:
+		return d.ends[index]
+	default:
+		return index
+	}
+}
+
+// text returns the raw input of the value at the given index.
+func (d *Document) text(index int) []byte {
+	e := d.elements[index]
+	switch e.tpe {
+	case tString:
+		if isIdentifierToken(d.data, e) {
+			size, err := findEndOfIdentifier(d.data, e.offset, len(d.data))
+			if err != nil {
+				return nil
+			}
+			return d.data[e.offset : e.offset+size]
+		}
+		size, err := findMatchingQuotes(d.data, e.offset, len(d.data), d.syntax)
+		if err != nil {
+			return nil
+		}
+		return d.data[e.offset : e.offset+size]
+	case tNumber:
+		size, err := findEndOfNumber(d.data, e.offset, len(d.data), d.syntax)
+		if err != nil {
+			return nil
+		}
+		return d.data[e.offset : e.offset+size]
+	case tNull:
+		return d.data[e.offset : e.offset+4]
+	case tBool:
+		if d.data[e.offset] == 't' {
+			return d.data[e.offset : e.offset+4]
+		}
+		return d.data[e.offset : e.offset+5]
+	case tObjectStart, tArrayStart:
+		last := d.elements[d.end(index)]
+		return d.data[e.offset : last.offset+1]
+	case tComment:
+		size, err := findEndOfComment(d.data, e.offset, len(d.data))
+		if err != nil {
+			return nil
+		}
+		return d.data[e.offset : e.offset+size]
+	default:
+		return nil
+	}
+}
+
+// numberText returns the number at the given index in JSON syntax. Numbers of
+// JSON5 documents are converted, except for Infinity and NaN.
+func (d *Document) numberText(index int) []byte {
+	token := d.text(index)
+	if d.syntax != SyntaxJSON5 || len(token) == 0 {
+		return token
+	}
+	return normalizeJSON5Number(token)
+}
+
+// stringValue returns the unescaped content of the string at the given index.
+// In JSON5 documents, this may be an unquoted member name.
+func (d *Document) stringValue(index int) (string, error) {
+	if isIdentifierToken(d.data, d.elements[index]) {
+		return decodeIdentifier(d.text(index)), nil
+	}
+	return unquote(d.text(index))
+}
+
+// boolValue returns the value of the boolean at the given index.
+func (d *Document) boolValue(index int) bool {
+	return d.data[d.elements[index].offset] == 't'
+}
+
+// ErrInvalidEscape signals an escape sequence that can't be decoded.
+var ErrInvalidEscape = errors.New("invalid escape sequence")
+
+// unquote decodes a string token including the surrounding quotes. Invalid
+// UTF-8 and unpaired surrogates are replaced with U+FFFD, like encoding/json
+// does. The single quotes and escape sequences of JSON5 are decoded, too.
+func unquote(token []byte) (string, error) {
+	if len(token) < 2 || (token[0] != '"' && token[0] != '\'') || token[len(token)-1] != token[0] {
+		return "", ErrInvalidToken
+	}
+	s := token[1 : len(token)-1]
+
+	// fast path for strings without escape sequences
+	escaped := false
+	for _, c := range s {
+		if c == '\\' {
+			escaped = true
+			break
+		}
+	}
+	if !escaped && utf8.Valid(s) {
+		return string(s), nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			// invalid sequences are replaced byte by byte
+			r, size := utf8.DecodeRune(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				b.WriteRune(utf8.RuneError)
+			} else {
+				b.Write(s[i : i+size])
+			}
+			i += size - 1
+			continue
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", ErrInvalidEscape
+		}
+		switch s[i] {
+		case '"', '\\', '/':
+			b.WriteByte(s[i])
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '\'':
+			b.WriteByte('\'')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case 'x':
+			r, ok := decodeHex2(s[i+1:])
+			if !ok {
+				return "", ErrInvalidEscape
+			}
+			i += 2
+			b.WriteRune(r)
+		case '\n', '\r':
+			// line continuation
+			if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case 'u':
+			r, ok := decodeHex4(s[i+1:])
+			if !ok {
+				return "", ErrInvalidEscape
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				// try to combine with a following low surrogate
+				if i+6 < len(s) && s[i+1] == '\\' && s[i+2] == 'u' {
+					if r2, ok := decodeHex4(s[i+3:]); ok {
+						if combined := utf16.DecodeRune(r, r2); combined != utf8.RuneError {
+							b.WriteRune(combined)
+							i += 6
+							continue
+						}
+					}
+				}
+				r = utf8.RuneError
+			}
+			b.WriteRune(r)
+		default:
+			// any other character stands for itself in JSON5, except for
+			// line terminators, which continue the line
+			r, size := utf8.DecodeRune(s[i:])
+			if r != 0x2028 && r != 0x2029 {
+				b.WriteRune(r)
+			}
+			i += size - 1
+		}
+	}
+	return b.String(), nil
+}
+
+// decodeHex4 decodes the four hex digits at the start of the data.
+func decodeHex4(data []byte) (rune, bool) {
+	return decodeHex(data, 4)
+}
+
+// decodeHex2 decodes the two hex digits at the start of the data.
+func decodeHex2(data []byte) (rune, bool) {
+	return decodeHex(data, 2)
+}
+
+// decodeHex decodes the given number of hex digits at the start of the data.
+func decodeHex(data []byte, count int) (rune, bool) {
+	if len(data) < count {
+		return 0, false
+	}
+	var r rune
+	for _, c := range data[:count] {
+		switch {
+		case '0' <= c && c <= '9':
+			r = r*16 + rune(c-'0')
+		case 'a' <= c && c <= 'f':
+			r = r*16 + rune(c-'a'+10)
+		case 'A' <= c && c <= 'F':
+			r = r*16 + rune(c-'A'+10)
+		default:
+			return 0, false
+		}
+	}
+	return r, true
+}
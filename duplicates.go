@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+)
+
+// DuplicateKeyPolicy determines how the parser treats objects with multiple
+// members of the same name. Names are compared after decoding the escape
+// sequences, so "a" and "\u0061" are duplicates.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysAllow keeps all members. This is the default.
+	DuplicateKeysAllow DuplicateKeyPolicy = iota
+	// DuplicateKeysReject fails with ErrDuplicateKey.
+	DuplicateKeysReject
+	// DuplicateKeysKeepFirst removes all but the first member of a name from
+	// the syntax tree.
+	DuplicateKeysKeepFirst
+	// DuplicateKeysKeepLast removes all but the last member of a name from
+	// the syntax tree.
+	DuplicateKeysKeepLast
+)
+
+// ErrDuplicateKey signals an object with multiple members of the same name.
+var ErrDuplicateKey = errors.New("duplicate object key")
+
+// objectMembers collects the members of an object while walking the syntax
+// tree.
+type objectMembers struct {
+	start int            // index of the object
+	key   bool           // whether the next string of the object is a key
+	seen  map[string]int // index of the kept member for every name
+	keys  []int          // indices of the member names
+	ends  []int          // indices of the last elements of the member values
+	keep  []bool         // whether the members are kept
+}
+
+// applyDuplicateKeyPolicy checks the objects in the syntax tree for duplicate
+// member names and handles them according to the policy of the options.
+// Removed members vanish from the syntax tree, but of course not from the
+// input data. It walks the syntax tree once, keeping the members of the
+// objects that are still open on a stack.
+func applyDuplicateKeyPolicy(data []byte, elements []JSONElement, opts ParseOptions) ([]JSONElement, error) {
+	policy := opts.DuplicateKeys
+	if policy == DuplicateKeysAllow {
+		return elements, nil
+	}
+
+	// the document is only used to decode the member names
+	d := &Document{data: data, elements: elements, syntax: opts.Syntax}
+	// dropped elements are marked by incrementing the count at the first and
+	// decrementing it after the last index of every dropped range
+	drop := make([]int, len(elements)+1)
+	stack := []*objectMembers{}
+	// index of the last element that isn't a comment
+	last := 0
+	for i, e := range elements {
+		if e.tpe == tComment {
+			continue
+		}
+		if e.tpe == tObjectEnd && len(stack) > 0 {
+			o := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(o.ends) < len(o.keys) {
+				o.ends = append(o.ends, last)
+			}
+			o.dropMembers(elements, drop)
+		}
+		if len(stack) > 0 && e.parent == stack[len(stack)-1].start {
+			o := stack[len(stack)-1]
+			switch {
+			case e.tpe == tComma:
+				o.ends = append(o.ends, last)
+				o.key = true
+			case e.tpe == tString && o.key:
+				o.key = false
+				key, err := d.stringValue(i)
+				if err != nil {
+					return nil, err
+				}
+				m := len(o.keys)
+				o.keys = append(o.keys, i)
+				o.keep = append(o.keep, true)
+				prev, ok := o.seen[key]
+				if !ok {
+					o.seen[key] = m
+					break
+				}
+				switch policy {
+				case DuplicateKeysReject:
+					// the first duplicate in the input
+					return nil, &ParseError{Offset: e.offset, Err: ErrDuplicateKey}
+				case DuplicateKeysKeepFirst:
+					o.keep[m] = false
+				case DuplicateKeysKeepLast:
+					o.keep[prev] = false
+					o.seen[key] = m
+				}
+			}
+		}
+		if e.tpe == tObjectStart {
+			stack = append(stack, &objectMembers{start: i, key: true, seen: map[string]int{}})
+		}
+		last = i
+	}
+
+	// compact the remaining elements, adjusting the parent indices
+	index := make([]int, len(elements))
+	res := make([]JSONElement, 0, len(elements))
+	dropped := 0
+	for i, e := range elements {
+		dropped += drop[i]
+		if dropped > 0 {
+			continue
+		}
+		index[i] = len(res)
+		e.parent = index[e.parent]
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// dropMembers marks the members of the object that aren't kept as dropped,
+// together with the commas that separate them.
+func (o *objectMembers) dropMembers(elements []JSONElement, drop []int) {
+	first := true
+	for m, kept := range o.keep {
+		key := o.keys[m]
+		if !kept {
+			// drop the key, the colon and the value
+			drop[key]++
+			drop[o.ends[m]+1]--
+		}
+		if m > 0 && (!kept || first) {
+			// drop the preceding comma, the first kept member has none
+			comma := key - 1
+			for elements[comma].tpe == tComment {
+				comma--
+			}
+			drop[comma]++
+			drop[comma+1]--
+		}
+		if kept {
+			first = false
+		}
+	}
+}
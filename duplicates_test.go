@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type duplicateKeysTest struct {
+	data   string
+	policy DuplicateKeyPolicy
+	// expected document, which must have the same syntax tree
+	expected string
+	// offset of the expected ErrDuplicateKey, if not negative
+	offset int
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	cases := map[string]duplicateKeysTest{
+		"allow": {
+			data:     `{"a": 1, "a": 2}`,
+			policy:   DuplicateKeysAllow,
+			expected: `{"a": 1, "a": 2}`,
+			offset:   -1,
+		},
+		"reject": {
+			data:   `{"a": 1, "b": 2, "a": 3}`,
+			policy: DuplicateKeysReject,
+			offset: 17,
+		},
+		"reject escaped": {
+			data:   `{"a": 1, "\u0061": 2}`,
+			policy: DuplicateKeysReject,
+			offset: 9,
+		},
+		"reject first in input": {
+			data:   `{"x": {"a": 1, "a": 2}, "x": 3}`,
+			policy: DuplicateKeysReject,
+			offset: 15,
+		},
+		"reject unique": {
+			data:     `{"a": {"a": 1}, "b": [{"a": 1}, {"a": 2}]}`,
+			policy:   DuplicateKeysReject,
+			expected: `{"a": {"a": 1}, "b": [{"a": 1}, {"a": 2}]}`,
+			offset:   -1,
+		},
+		"keep first": {
+			data:     `{"a": 1, "b": 2, "a": [3, {"c": 4}]}`,
+			policy:   DuplicateKeysKeepFirst,
+			expected: `{"a": 1, "b": 2}`,
+			offset:   -1,
+		},
+		"keep first trailing": {
+			data:     `{"a": 1, "b": 2, "a": 3, "a": 4}`,
+			policy:   DuplicateKeysKeepFirst,
+			expected: `{"a": 1, "b": 2}`,
+			offset:   -1,
+		},
+		"keep last": {
+			data:     `{"a": {"c": [1]}, "b": 2, "a": 3}`,
+			policy:   DuplicateKeysKeepLast,
+			expected: `{"b": 2, "a": 3}`,
+			offset:   -1,
+		},
+		"keep last leading": {
+			data:     `{"a": 1, "a": 2, "a": 3, "b": 4}`,
+			policy:   DuplicateKeysKeepLast,
+			expected: `{"a": 3, "b": 4}`,
+			offset:   -1,
+		},
+		"keep nested": {
+			data:     `[{"a": 1, "a": {"b": 2, "b": 3}}, 4]`,
+			policy:   DuplicateKeysKeepLast,
+			expected: `[{"a": {"b": 3}}, 4]`,
+			offset:   -1,
+		},
+		"keep last deeply nested": {
+			data:     strings.Repeat(`{"a": 1, "a": `, 3000) + `2` + strings.Repeat(`}`, 3000),
+			policy:   DuplicateKeysKeepLast,
+			expected: strings.Repeat(`{"a": `, 3000) + `2` + strings.Repeat(`}`, 3000),
+			offset:   -1,
+		},
+		"keep first deeply nested": {
+			data:     strings.Repeat(`{"a": 1, "a": `, 3000) + `2` + strings.Repeat(`}`, 3000),
+			policy:   DuplicateKeysKeepFirst,
+			expected: `{"a": 1}`,
+			offset:   -1,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseDocumentOptions([]byte(c.data), ParseOptions{DuplicateKeys: c.policy})
+			if c.offset >= 0 {
+				var e *ParseError
+				if !errors.As(err, &e) || !errors.Is(err, ErrDuplicateKey) {
+					t.Fatal("expected ErrDuplicateKey, received", err)
+				}
+				if e.Offset != c.offset {
+					t.Errorf("expected offset %d, received %d", c.offset, e.Offset)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+
+			expected, err := ParseDocument([]byte(c.expected))
+			if err != nil {
+				t.Fatal("invalid expected document", err)
+			}
+			if len(doc.elements) != len(expected.elements) {
+				t.Fatalf("expected %d elements, received %d", len(expected.elements), len(doc.elements))
+			}
+			for i, e := range expected.elements {
+				received := doc.elements[i]
+				if received.tpe != e.tpe || received.parent != e.parent {
+					t.Fatalf("element %d differs, expected %v, received %v", i, e, received)
+				}
+			}
+			if !EqualDocuments(doc, expected) {
+				t.Error("documents differ")
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrByteOrderMark signals a byte order mark at the start of the input, which
+// is only reported if the parser is configured to reject it.
+var ErrByteOrderMark = errors.New("byte order mark")
+
+// ErrInvalidEncoding signals input that isn't valid in the detected encoding,
+// e.g. unpaired surrogates in UTF-16 input.
+var ErrInvalidEncoding = errors.New("invalid character encoding")
+
+// textEncoding is a Unicode encoding of the input data.
+type textEncoding int
+
+const (
+	encUTF8 textEncoding = iota
+	encUTF16BE
+	encUTF16LE
+	encUTF32BE
+	encUTF32LE
+)
+
+// unitSize returns the size of the code units of the encoding in bytes.
+func (e textEncoding) unitSize() int {
+	switch e {
+	case encUTF16BE, encUTF16LE:
+		return 2
+	case encUTF32BE, encUTF32LE:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// detectEncoding determines the encoding of the input data and the size of
+// its byte order mark. Without byte order mark, the encoding is derived from
+// the pattern of null bytes, because the first two characters of a JSON text
+// are ASCII characters (RFC 4627, section 3).
+func detectEncoding(data []byte) (textEncoding, int) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x00, 0x00, 0xfe, 0xff}):
+		return encUTF32BE, 4
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe, 0x00, 0x00}):
+		return encUTF32LE, 4
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return encUTF16BE, 2
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return encUTF16LE, 2
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return encUTF8, 3
+	}
+
+	switch {
+	case len(data) >= 4:
+		switch {
+		case data[0] == 0 && data[1] == 0 && data[2] == 0 && data[3] != 0:
+			return encUTF32BE, 0
+		case data[0] != 0 && data[1] == 0 && data[2] == 0 && data[3] == 0:
+			return encUTF32LE, 0
+		case data[0] == 0 && data[1] != 0 && data[2] == 0 && data[3] != 0:
+			return encUTF16BE, 0
+		case data[0] != 0 && data[1] == 0 && data[2] != 0 && data[3] == 0:
+			return encUTF16LE, 0
+		}
+	case len(data) >= 2:
+		// a single character in UTF-16
+		switch {
+		case data[0] == 0 && data[1] != 0:
+			return encUTF16BE, 0
+		case data[0] != 0 && data[1] == 0:
+			return encUTF16LE, 0
+		}
+	}
+	return encUTF8, 0
+}
+
+// input is the input data of the parser together with its UTF-8 text.
+type input struct {
+	data []byte       // original input data
+	text []byte       // input data without byte order mark, converted to UTF-8
+	enc  textEncoding // encoding of the input data
+	bom  int          // size of the byte order mark
+}
+
+// prepareInput checks the size of the input data and decodes it. It returns
+// the options for parsing the decoded text, which don't check the size again,
+// since the limit applies to the original data.
+func prepareInput(data []byte, opts ParseOptions) (*input, ParseOptions, error) {
+	if opts.MaxInputSize > 0 && len(data) > opts.MaxInputSize {
+		return nil, opts, &ParseError{Offset: opts.MaxInputSize, Err: ErrInputTooLarge}
+	}
+	in, err := decodeInput(data, opts)
+	if err != nil {
+		return nil, opts, err
+	}
+	opts.MaxInputSize = 0
+	return in, opts, nil
+}
+
+// decodeInput detects the encoding of the input data and converts it to UTF-8.
+func decodeInput(data []byte, opts ParseOptions) (*input, error) {
+	enc, bom := detectEncoding(data)
+	if bom > 0 && opts.RejectBOM {
+		return nil, &ParseError{Offset: 0, Err: ErrByteOrderMark}
+	}
+	in := &input{data: data, enc: enc, bom: bom}
+	if enc == encUTF8 {
+		in.text = data[bom:]
+		return in, nil
+	}
+
+	text := make([]byte, 0, len(data)-bom)
+	for cur := bom; cur < len(data); {
+		r, n, ok := in.decodeRune(cur)
+		if !ok && !opts.ReplaceInvalidUTF8 {
+			return nil, &ParseError{Offset: cur, Err: ErrInvalidEncoding}
+		}
+		text = utf8.AppendRune(text, r)
+		cur += n
+	}
+	in.text = text
+	return in, nil
+}
+
+// decodeRune decodes the character at the given offset of the UTF-16 or
+// UTF-32 input data. It returns the character and the number of bytes it
+// occupies. Invalid characters are returned as utf8.RuneError and false.
+func (in *input) decodeRune(cur int) (rune, int, bool) {
+	size := in.enc.unitSize()
+	if cur+size > len(in.data) {
+		// incomplete code unit
+		return utf8.RuneError, len(in.data) - cur, false
+	}
+
+	unit := func(offset int) rune {
+		switch in.enc {
+		case encUTF16BE:
+			return rune(binary.BigEndian.Uint16(in.data[offset:]))
+		case encUTF16LE:
+			return rune(binary.LittleEndian.Uint16(in.data[offset:]))
+		case encUTF32BE:
+			return rune(binary.BigEndian.Uint32(in.data[offset:]))
+		default:
+			return rune(binary.LittleEndian.Uint32(in.data[offset:]))
+		}
+	}
+
+	r := unit(cur)
+	if size == 4 {
+		if r < 0 || r > utf8.MaxRune || utf16.IsSurrogate(r) {
+			return utf8.RuneError, size, false
+		}
+		return r, size, true
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, size, true
+	}
+	if cur+2*size <= len(in.data) {
+		if combined := utf16.DecodeRune(r, unit(cur+size)); combined != utf8.RuneError {
+			return combined, 2 * size, true
+		}
+	}
+	return utf8.RuneError, size, false
+}
+
+// originalOffset converts an offset in the text to the offset in the input
+// data.
+func (in *input) originalOffset(offset int) int {
+	if in.enc == encUTF8 {
+		return in.bom + offset
+	}
+	cur := in.bom
+	for pos := 0; pos < offset && cur < len(in.data); {
+		r, n, _ := in.decodeRune(cur)
+		pos += utf8.RuneLen(r)
+		cur += n
+	}
+	return cur
+}
+
+// mapError converts the offset of a ParseError from the text to the input
+// data.
+func (in *input) mapError(err error) error {
+	var e *ParseError
+	if !errors.As(err, &e) {
+		return err
+	}
+	return &ParseError{Offset: in.originalOffset(e.Offset), Err: e.Err}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16LE encodes the string as UTF-16 with little endian byte order.
+func utf16LE(s string) []byte {
+	res := []byte{}
+	for _, u := range utf16.Encode([]rune(s)) {
+		res = append(res, byte(u), byte(u>>8))
+	}
+	return res
+}
+
+// utf16BE encodes the string as UTF-16 with big endian byte order.
+func utf16BE(s string) []byte {
+	res := []byte{}
+	for _, u := range utf16.Encode([]rune(s)) {
+		res = append(res, byte(u>>8), byte(u))
+	}
+	return res
+}
+
+// utf32LE encodes the string as UTF-32 with little endian byte order.
+func utf32LE(s string) []byte {
+	res := []byte{}
+	for _, r := range s {
+		res = append(res, byte(r), byte(r>>8), byte(r>>16), byte(r>>24))
+	}
+	return res
+}
+
+// utf32BE encodes the string as UTF-32 with big endian byte order.
+func utf32BE(s string) []byte {
+	res := []byte{}
+	for _, r := range s {
+		res = append(res, byte(r>>24), byte(r>>16), byte(r>>8), byte(r))
+	}
+	return res
+}
+
+func concat(parts ...[]byte) []byte {
+	res := []byte{}
+	for _, p := range parts {
+		res = append(res, p...)
+	}
+	return res
+}
+
+type encodingTest struct {
+	data     []byte
+	opts     ParseOptions
+	expected string // equivalent UTF-8 document, if accepted
+	err      error  // expected error, nil if none
+	offset   int
+}
+
+func TestParseDocumentEncoding(t *testing.T) {
+	const doc = `{"k": ["ä€😀", 1]}`
+	bomUTF8 := []byte{0xef, 0xbb, 0xbf}
+	duplicates := ParseOptions{DuplicateKeys: DuplicateKeysReject}
+
+	cases := map[string]encodingTest{
+		"utf-8":             {data: []byte(doc), expected: doc},
+		"utf-8 bom":         {data: concat(bomUTF8, []byte(doc)), expected: doc},
+		"utf-16le":          {data: utf16LE(doc), expected: doc},
+		"utf-16le bom":      {data: concat([]byte{0xff, 0xfe}, utf16LE(doc)), expected: doc},
+		"utf-16be":          {data: utf16BE(doc), expected: doc},
+		"utf-16be bom":      {data: concat([]byte{0xfe, 0xff}, utf16BE(doc)), expected: doc},
+		"utf-32le":          {data: utf32LE(doc), expected: doc},
+		"utf-32le bom":      {data: concat([]byte{0xff, 0xfe, 0, 0}, utf32LE(doc)), expected: doc},
+		"utf-32be":          {data: utf32BE(doc), expected: doc},
+		"utf-32be bom":      {data: concat([]byte{0, 0, 0xfe, 0xff}, utf32BE(doc)), expected: doc},
+		"utf-16 one digit":  {data: utf16LE(`1`), expected: `1`},
+		"utf-16 whitespace": {data: utf16BE(" \"\uffff\" "), expected: "\"\uffff\""},
+		"reject bom": {
+			data: concat(bomUTF8, []byte(doc)),
+			opts: ParseOptions{RejectBOM: true},
+			err:  ErrByteOrderMark,
+		},
+		"reject utf-16 bom": {
+			data: concat([]byte{0xff, 0xfe}, utf16LE(doc)),
+			opts: ParseOptions{RejectBOM: true},
+			err:  ErrByteOrderMark,
+		},
+		"lone surrogate": {
+			data:   concat(utf16LE(`["a`), []byte{0x00, 0xd8}, utf16LE(`"]`)),
+			err:    ErrInvalidEncoding,
+			offset: 6,
+		},
+		"lone surrogate replaced": {
+			data:     concat(utf16LE(`["a`), []byte{0x00, 0xd8}, utf16LE(`"]`)),
+			opts:     ParseOptions{ReplaceInvalidUTF8: true},
+			expected: "[\"a\ufffd\"]",
+		},
+		"odd length": {
+			data:   concat(utf16BE(`"a"`), []byte{0}),
+			err:    ErrInvalidEncoding,
+			offset: 6,
+		},
+		"utf-32 out of range": {
+			data:   concat(utf32BE(`"`), []byte{0, 0x11, 0, 0}, utf32BE(`"`)),
+			err:    ErrInvalidEncoding,
+			offset: 4,
+		},
+		"offset utf-8 bom": {
+			data:   concat(bomUTF8, []byte(`{"a": 1, "a": 2}`)),
+			opts:   duplicates,
+			err:    ErrDuplicateKey,
+			offset: 12,
+		},
+		"offset utf-16": {
+			data:   concat([]byte{0xff, 0xfe}, utf16LE(`{"ä":1,"😀":2,"ä":3}`)),
+			opts:   duplicates,
+			err:    ErrDuplicateKey,
+			offset: 2 + 2*14,
+		},
+		"offset utf-32": {
+			data:   utf32BE(`{"ä":1,"😀":2,"ä":3}`),
+			opts:   duplicates,
+			err:    ErrDuplicateKey,
+			offset: 4 * 13,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseDocumentOptions(c.data, c.opts)
+			if c.err != nil {
+				var e *ParseError
+				if !errors.As(err, &e) || !errors.Is(err, c.err) {
+					t.Fatalf("expected error %v, received %v", c.err, err)
+				}
+				if e.Offset != c.offset {
+					t.Errorf("expected offset %d, received %d", c.offset, e.Offset)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			expected, err := ParseDocument([]byte(c.expected))
+			if err != nil {
+				t.Fatal("invalid expected document", err)
+			}
+			if !EqualDocuments(doc, expected) {
+				t.Errorf("expected %s, received %s", c.expected, doc.data)
+			}
+		})
+	}
+}
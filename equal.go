@@ -0,0 +1,95 @@
+package main
+
+// Equal tells whether the two inputs contain semantically equal JSON values.
+// In contrast to comparing the raw data, whitespace and the order of object
+// keys are ignored, strings are compared after unescaping and numbers are
+// compared by their exact value, so that "1.0" equals "10e-1".
+// An error is returned if either input fails to parse.
+func Equal(a, b []byte) (bool, error) {
+	da, err := ParseDocument(a)
+	if err != nil {
+		return false, err
+	}
+	db, err := ParseDocument(b)
+	if err != nil {
+		return false, err
+	}
+	return EqualDocuments(da, db), nil
+}
+
+// EqualDocuments tells whether the two documents are semantically equal,
+// see Equal for the rules.
+func EqualDocuments(a, b *Document) bool {
+	ra := a.root()
+	rb := b.root()
+	if ra == 0 || rb == 0 {
+		// at least one document is empty
+		return ra == rb
+	}
+	return equalValues(a, ra, b, rb)
+}
+
+// equalValues compares the value at index ia in document a with the value at
+// index ib in document b.
+func equalValues(a *Document, ia int, b *Document, ib int) bool {
+	ta := a.elements[ia].tpe
+	tb := b.elements[ib].tpe
+	if ta != tb {
+		return false
+	}
+
+	switch ta {
+	case tNull:
+		return true
+	case tBool:
+		return a.boolValue(ia) == b.boolValue(ib)
+	case tNumber:
+		return canonicalNumber(a.numberText(ia)) == canonicalNumber(b.numberText(ib))
+	case tString:
+		sa, err := a.stringValue(ia)
+		if err != nil {
+			return false
+		}
+		sb, err := b.stringValue(ib)
+		if err != nil {
+			return false
+		}
+		return sa == sb
+	case tArrayStart:
+		ca := a.children(ia)
+		cb := b.children(ib)
+		if len(ca) != len(cb) {
+			return false
+		}
+		for i := range ca {
+			if !equalValues(a, ca[i], b, cb[i]) {
+				return false
+			}
+		}
+		return true
+	case tObjectStart:
+		ma, err := a.members(ia)
+		if err != nil {
+			return false
+		}
+		mb, err := b.members(ib)
+		if err != nil {
+			return false
+		}
+		if len(ma) != len(mb) {
+			return false
+		}
+		for key, va := range ma {
+			vb, ok := mb[key]
+			if !ok {
+				return false
+			}
+			if !equalValues(a, va, b, vb) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+type equalTest struct {
+	a     []byte
+	b     []byte
+	equal bool
+	err   bool
+}
+
+func TestEqual(t *testing.T) {
+	cases := map[string]equalTest{
+		"empty": {
+			a:     []byte(``),
+			b:     []byte(` `),
+			equal: true,
+		},
+		"empty and null": {
+			a:     []byte(``),
+			b:     []byte(`null`),
+			equal: false,
+		},
+		"null": {
+			a:     []byte(`null`),
+			b:     []byte(` null `),
+			equal: true,
+		},
+		"bool 1": {
+			a:     []byte(`true`),
+			b:     []byte(`true`),
+			equal: true,
+		},
+		"bool 2": {
+			a:     []byte(`true`),
+			b:     []byte(`false`),
+			equal: false,
+		},
+		"type mismatch": {
+			a:     []byte(`1`),
+			b:     []byte(`"1"`),
+			equal: false,
+		},
+		"number 1": {
+			a:     []byte(`1`),
+			b:     []byte(`1.0`),
+			equal: true,
+		},
+		"number 2": {
+			a:     []byte(`1.5`),
+			b:     []byte(`15e-1`),
+			equal: true,
+		},
+		"number 3": {
+			a:     []byte(`0`),
+			b:     []byte(`-0.0e10`),
+			equal: true,
+		},
+		"number 4": {
+			a:     []byte(`100`),
+			b:     []byte(`1E+2`),
+			equal: true,
+		},
+		"number 5": {
+			a:     []byte(`-100`),
+			b:     []byte(`100`),
+			equal: false,
+		},
+		"number 6": {
+			a:     []byte(`12345678901234567890123456789`),
+			b:     []byte(`12345678901234567890123456788`),
+			equal: false,
+		},
+		"number 7": {
+			a:     []byte(`1e100000000000000000000`),
+			b:     []byte(`10e99999999999999999999`),
+			equal: true,
+		},
+		"string 1": {
+			a:     []byte(`"a/b"`),
+			b:     []byte(`"a\/b"`),
+			equal: true,
+		},
+		"string 2": {
+			a:     []byte(`"ä"`),
+			b:     []byte(`"\u00e4"`),
+			equal: true,
+		},
+		"string 3": {
+			a:     []byte(`"😀"`),
+			b:     []byte(`"\ud83d\ude00"`),
+			equal: true,
+		},
+		"string 4": {
+			a:     []byte(`"a"`),
+			b:     []byte(`"A"`),
+			equal: false,
+		},
+		"array 1": {
+			a:     []byte(`[1, 2, 3]`),
+			b:     []byte(`[1,2,3]`),
+			equal: true,
+		},
+		"array 2": {
+			a:     []byte(`[1, 2, 3]`),
+			b:     []byte(`[3, 2, 1]`),
+			equal: false,
+		},
+		"array 3": {
+			a:     []byte(`[1, 2]`),
+			b:     []byte(`[1, 2, 3]`),
+			equal: false,
+		},
+		"array 4": {
+			a:     []byte(`[[], [[1]]]`),
+			b:     []byte(`[[], [[1.0]]]`),
+			equal: true,
+		},
+		"object 1": {
+			a:     []byte(`{"a": 1, "b": 2}`),
+			b:     []byte(`{"b": 2, "a": 1}`),
+			equal: true,
+		},
+		"object 2": {
+			a:     []byte(`{"a": 1, "b": 2}`),
+			b:     []byte(`{"a": 1}`),
+			equal: false,
+		},
+		"object 3": {
+			a:     []byte(`{"a": 1, "b": 2}`),
+			b:     []byte(`{"a": 1, "c": 2}`),
+			equal: false,
+		},
+		"object 4": {
+			a:     []byte(`{"a": {"x": [true]}}`),
+			b:     []byte(`{"a": {"x": [true]}}`),
+			equal: true,
+		},
+		"deeply nested 1": {
+			a:     []byte(strings.Repeat(`{"a": [`, 4000) + `1` + strings.Repeat(`]}`, 4000)),
+			b:     []byte(strings.Repeat(`{"a": [`, 4000) + `1.0` + strings.Repeat(`]}`, 4000)),
+			equal: true,
+		},
+		"deeply nested 2": {
+			a:     []byte(strings.Repeat(`{"a": [`, 4000) + `1` + strings.Repeat(`]}`, 4000)),
+			b:     []byte(strings.Repeat(`{"a": [`, 4000) + `2` + strings.Repeat(`]}`, 4000)),
+			equal: false,
+		},
+		"invalid 1": {
+			a:   []byte(`[1,]`),
+			b:   []byte(`[]`),
+			err: true,
+		},
+		"invalid 2": {
+			a:   []byte(`[]`),
+			b:   []byte(`nil`),
+			err: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			equal, err := Equal(c.a, c.b)
+			if c.err {
+				if err == nil {
+					t.Error("expected error missing")
+				}
+				return
+			}
+			if err != nil {
+				t.Error("unexpected failure", err)
+				return
+			}
+			if equal != c.equal {
+				t.Errorf("expected %v, received %v", c.equal, equal)
+			}
+			// equality must be symmetric
+			if reverse, _ := Equal(c.b, c.a); reverse != equal {
+				t.Error("comparison is not symmetric")
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// structField describes a struct field that is mapped to a JSON object member.
+// Fields of embedded structs are promoted like in encoding/json.
+type structField struct {
+	name      string       // name of the JSON object member
+	tagged    bool         // whether the name comes from a tag
+	index     []int        // index sequence for reflect.Value.FieldByIndex
+	typ       reflect.Type // type of the field
+	omitEmpty bool         // whether the tag contains "omitempty"
+	asString  bool         // whether the tag contains "string" and it applies to the type
+}
+
+// structFields is the set of fields of a struct type.
+type structFields struct {
+	list   []structField
+	byName map[string]int // index in list by exact name
+}
+
+// fieldCache maps a reflect.Type to its *structFields.
+var fieldCache sync.Map
+
+// cachedTypeFields returns the fields of the struct type, computing them only
+// once per type.
+func cachedTypeFields(t reflect.Type) *structFields {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(*structFields)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.(*structFields)
+}
+
+// lookup finds the field for a member name. An exact match is preferred, but
+// like encoding/json, a case-insensitive match is accepted, too.
+func (s *structFields) lookup(name string) *structField {
+	if i, ok := s.byName[name]; ok {
+		return &s.list[i]
+	}
+	for i := range s.list {
+		if strings.EqualFold(s.list[i].name, name) {
+			return &s.list[i]
+		}
+	}
+	return nil
+}
+
+// parseTag splits a "json" struct tag into the name and its options.
+func parseTag(tag string) (string, string) {
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// hasOption tells whether the comma-separated options contain the given one.
+func hasOption(options, option string) bool {
+	for options != "" {
+		var next string
+		if i := strings.IndexByte(options, ','); i >= 0 {
+			options, next = options[:i], options[i+1:]
+		}
+		if options == option {
+			return true
+		}
+		options = next
+	}
+	return false
+}
+
+// isValidTag tells whether the name from a tag can be used, using the same
+// rules as encoding/json.
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// allowed punctuation
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// typeFields computes the fields of a struct type, following the rules of
+// encoding/json for embedded structs: Fields of embedded structs are promoted
+// unless they are shadowed by a field with the same name at a shallower depth.
+// If there are several fields with the same name at the same depth, a tagged
+// one wins, otherwise all of them are ignored.
+func typeFields(t reflect.Type) *structFields {
+	// breadth-first search over the embedded structs
+	current := []structField{}
+	next := []structField{{typ: t}}
+	count := map[reflect.Type]int{}
+	nextCount := map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+
+	fields := []structField{}
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+
+			for i := 0; i < f.typ.NumField(); i++ {
+				sf := f.typ.Field(i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					// embedded unexported non-struct types are ignored
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					// unexported field
+					continue
+				}
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options := parseTag(tag)
+				if !isValidTag(name) {
+					name = ""
+				}
+				index := make([]int, len(f.index)+1)
+				copy(index, f.index)
+				index[len(f.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				// the "string" option only applies to scalar types
+				asString := false
+				if hasOption(options, "string") {
+					switch ft.Kind() {
+					case reflect.Bool,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+						reflect.Float32, reflect.Float64,
+						reflect.String:
+						asString = true
+					}
+				}
+
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					field := structField{
+						name:      name,
+						tagged:    name != "",
+						index:     index,
+						typ:       sf.Type,
+						omitEmpty: hasOption(options, "omitempty"),
+						asString:  asString,
+					}
+					if field.name == "" {
+						field.name = sf.Name
+					}
+					fields = append(fields, field)
+					if count[f.typ] > 1 {
+						// The same struct is embedded several times at this
+						// depth, so its fields annihilate each other. Adding
+						// the field twice takes care of that below.
+						fields = append(fields, field)
+					}
+					continue
+				}
+
+				// descend into the embedded struct in the next round
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, structField{name: ft.Name(), index: index, typ: ft})
+				}
+			}
+		}
+	}
+
+	// sort by name, then by depth, then tagged before untagged
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if len(a.index) != len(b.index) {
+			return len(a.index) < len(b.index)
+		}
+		if a.tagged != b.tagged {
+			return a.tagged
+		}
+		return lessIndex(a.index, b.index)
+	})
+
+	// remove shadowed and conflicting fields
+	res := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		group := fields[i:j]
+		i = j
+		if len(group) > 1 && len(group[0].index) == len(group[1].index) && group[0].tagged == group[1].tagged {
+			// ambiguous, ignore all of them
+			continue
+		}
+		res = append(res, group[0])
+	}
+
+	// restore the declaration order
+	sort.Slice(res, func(i, j int) bool {
+		return lessIndex(res[i].index, res[j].index)
+	})
+
+	byName := make(map[string]int, len(res))
+	for i, f := range res {
+		byName[f.name] = i
+	}
+	return &structFields{list: res, byName: byName}
+}
+
+// lessIndex orders index sequences by declaration order.
+func lessIndex(a, b []int) bool {
+	for k := range a {
+		if k >= len(b) {
+			return false
+		}
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"runtime/debug"
+)
+
+// ErrFileChanged signals that a file parsed by ParseFile was truncated while
+// parsing, so that its mapping could no longer be read.
+var ErrFileChanged = errors.New("file changed while parsing")
+
+// File is a document parsed from a file by ParseFile. Its data refers to the
+// memory mapping of the file, so it must be closed to release it.
+type File struct {
+	*Document
+	release func() error
+}
+
+// ParseFile parses the file at the given path. On Linux, the file is mapped
+// into memory read-only instead of copying it, so that even files of several
+// gigabytes only take the memory for the syntax tree.
+//
+// Like for ParseDocument, offsets of the elements refer to the decoded text:
+// they equal offsets in the file only for UTF-8 without a byte order mark.
+// With a byte order mark, they are 3 bytes before the offsets in the file, and
+// UTF-16 and UTF-32 files are converted to UTF-8 in memory. Offsets of a
+// ParseError refer to the file.
+//
+// The mapping reflects changes to the file. If the file is truncated while it
+// is parsed, ErrFileChanged is returned, unless it is parsed with Parallelism.
+// Otherwise truncating it crashes the program with SIGBUS, as does truncating
+// it while the document is still used. So files that may be changed by other
+// processes should rather be read with os.ReadFile and ParseDocument.
+func ParseFile(path string) (*File, error) {
+	return ParseFileOptions(path, ParseOptions{})
+}
+
+// ParseFileOptions parses the file at the given path like ParseFile, with the
+// parser configured by the options.
+func ParseFileOptions(path string, opts ParseOptions) (*File, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseMapped(data, opts)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &File{Document: doc, release: release}, nil
+}
+
+// parseMapped parses the mapped data of a file. Reading pages of the mapping
+// beyond the end of the file, which was truncated in the meantime, faults; the
+// fault is turned into ErrFileChanged instead of crashing the program. This
+// only works on the calling goroutine, not on those of parseJSONParallel.
+func parseMapped(data []byte, opts ParseOptions) (doc *Document, err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if _, ok := r.(interface{ Addr() uintptr }); !ok {
+			panic(r)
+		}
+		doc, err = nil, ErrFileChanged
+	}()
+	return ParseDocumentOptions(data, opts)
+}
+
+// Close unmaps the file. The document must not be used afterwards, values
+// taken from it must be copied before.
+func (f *File) Close() error {
+	if f.release == nil {
+		return nil
+	}
+	err := f.release()
+	f.release = nil
+	f.Document = nil
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	cases := map[string]struct {
+		data string
+		opts ParseOptions
+		err  error
+	}{
+		"object":          {data: `{"a": [1, 2.5, "x"], "b": null}`},
+		"empty":           {data: ``},
+		"byte order mark": {data: "\ufeff[true]"},
+		"utf16":           {data: string(utf16LE(`{"a": 1}`))},
+		"options":         {data: `[1, /* c */ 2,]`, opts: ParseOptions{Syntax: SyntaxJSONC}},
+		"invalid":         {data: `[1 2]`, err: ErrInvalidStructure},
+		"too large":       {data: `[1, 2]`, opts: ParseOptions{MaxInputSize: 3}, err: ErrInputTooLarge},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "doc.json")
+			if err := os.WriteFile(path, []byte(c.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := ParseFileOptions(path, c.opts)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, received %v", c.err, err)
+			}
+			if err != nil {
+				return
+			}
+			expected, _ := ParseDocumentOptions([]byte(c.data), c.opts)
+			if fmt.Sprint(f.elements) != fmt.Sprint(expected.elements) {
+				t.Errorf("expected elements %v, received %v", expected.elements, f.elements)
+			}
+			if !EqualDocuments(f.Document, expected) {
+				t.Errorf("expected document %s, received %s", appendCompact(nil, expected), appendCompact(nil, f.Document))
+			}
+			if err := f.Close(); err != nil {
+				t.Error("unexpected failure closing", err)
+			}
+			if f.Document != nil {
+				t.Error("document still available after closing")
+			}
+			if err := f.Close(); err != nil {
+				t.Error("unexpected failure closing twice", err)
+			}
+		})
+	}
+
+	if _, err := ParseFile(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected error %v, received %v", os.ErrNotExist, err)
+	}
+
+	// files that can't be mapped are read
+	if runtime.GOOS == "linux" {
+		f, err := ParseFile("/dev/null")
+		if err != nil {
+			t.Fatal("unexpected failure", err)
+		}
+		f.Close()
+	}
+}
+
+func TestParseFileOffsets(t *testing.T) {
+	// offsets refer to the text after the byte order mark
+	path := filepath.Join(t.TempDir(), "doc.json")
+	if err := os.WriteFile(path, []byte("\ufeff{\"a\": [1, \"x\"]}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mapped, release, err := mapFile(path)
+	if err != nil {
+		t.Fatal("unexpected failure mapping", err)
+	}
+	defer release()
+
+	f, err := ParseFile(path)
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	defer f.Close()
+	for _, elem := range f.elements[1:] {
+		if f.data[elem.offset] != mapped[elem.offset+3] {
+			t.Errorf("expected element at offset %d of the text to be at offset %d of the file", elem.offset, elem.offset+3)
+		}
+	}
+
+	// offsets of errors refer to the file
+	if err := os.WriteFile(path, []byte("\ufeff[1, [2]]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseFileOptions(path, ParseOptions{MaxDepth: 1})
+	var e *ParseError
+	if !errors.As(err, &e) || e.Offset != 7 {
+		t.Errorf("expected error at offset 7, received %v", err)
+	}
+}
+
+func TestParseFileTruncated(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("files are only mapped on Linux")
+	}
+	path := filepath.Join(t.TempDir(), "doc.json")
+	data := "[" + strings.Repeat("1, ", 1<<14) + "1]"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mapped, release, err := mapFile(path)
+	if err != nil {
+		t.Fatal("unexpected failure mapping", err)
+	}
+	defer release()
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseMapped(mapped, ParseOptions{}); !errors.Is(err, ErrFileChanged) {
+		t.Errorf("expected error %v, received %v", ErrFileChanged, err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// addParseJSONSeeds adds the inputs of the parseJSON test cases to the seed
+// corpus of a fuzz test.
+func addParseJSONSeeds(f *testing.F) {
+	for _, c := range parseJSONCases {
+		f.Add(c.data)
+	}
+}
+
+func FuzzFindMatchingQuotes(f *testing.F) {
+	addParseJSONSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		size, err := findMatchingQuotes(data, 0, len(data), SyntaxJSON)
+		if err != nil {
+			return
+		}
+		if size < 2 || size > len(data) {
+			t.Fatalf("%q: size %d out of bounds", data, size)
+		}
+		if data[0] != '"' || data[size-1] != '"' {
+			t.Fatalf("%q: size %d doesn't end at quotes", data, size)
+		}
+		if !json.Valid(data[:size]) {
+			t.Fatalf("%q: accepted invalid string %q", data, data[:size])
+		}
+	})
+}
+
+func FuzzFindEndOfNumber(f *testing.F) {
+	addParseJSONSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		size, err := findEndOfNumber(data, 0, len(data), SyntaxJSON)
+		if err != nil {
+			return
+		}
+		if size < 1 || size > len(data) {
+			t.Fatalf("%q: size %d out of bounds", data, size)
+		}
+		if !json.Valid(data[:size]) {
+			t.Fatalf("%q: accepted invalid number %q", data, data[:size])
+		}
+	})
+}
+
+func FuzzParseJSON(f *testing.F) {
+	addParseJSONSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		elements, err := parseJSONOptions(data, compatOptions)
+		if !isCompatDeviation(data) && (err == nil) != json.Valid(data) {
+			t.Fatalf("%q: error %v disagrees with encoding/json", data, err)
+		}
+		if err != nil {
+			return
+		}
+		if _, err := parseJSON(data); err != nil && !errors.Is(err, ErrInvalidUTF8) && !errors.Is(err, ErrLoneSurrogate) {
+			t.Fatalf("%q: strict parsing failed with %v", data, err)
+		}
+
+		if len(elements) == 0 || elements[0].tpe != tRoot {
+			t.Fatalf("%q: missing root element", data)
+		}
+		for i, e := range elements[1:] {
+			i++
+			if e.offset < 0 || e.offset >= len(data) {
+				t.Fatalf("%q: element %d offset %d out of bounds", data, i, e.offset)
+			}
+			if e.parent < 0 || e.parent >= i {
+				t.Fatalf("%q: element %d parent %d isn't an earlier element", data, i, e.parent)
+			}
+			switch elements[e.parent].tpe {
+			case tRoot, tObjectStart, tArrayStart:
+			default:
+				t.Fatalf("%q: element %d parent %d isn't a container", data, i, e.parent)
+			}
+		}
+	})
+}
+
+func FuzzParseJSON5(f *testing.F) {
+	addParseJSONSeeds(f)
+	for _, c := range []string{"{a: 'b', // c\n d: [+.5, 0x1F, Infinity,],}", "/* a */ 'b\\\nc\\x41'"} {
+		f.Add([]byte(c))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := ParseDocumentOptions(data, ParseOptions{Syntax: SyntaxJSON5})
+		if err != nil {
+			return
+		}
+		for i, e := range doc.elements {
+			switch e.tpe {
+			case tString:
+				if _, err := doc.stringValue(i); err != nil {
+					t.Fatalf("%q: string %d can't be decoded: %v", data, i, err)
+				}
+			case tNumber:
+				if len(doc.numberText(i)) == 0 {
+					t.Fatalf("%q: number %d has no text", data, i)
+				}
+			}
+		}
+	})
+}
+
+func FuzzParseDocumentRecover(f *testing.F) {
+	addParseJSONSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		elements, err := parseJSON(data)
+		text, recovered, errs := parseJSONRecover(data, ParseOptions{})
+		if (err == nil) != (len(errs) == 0) {
+			t.Fatalf("%q: error %v disagrees with recovered errors %v", data, err, errs)
+		}
+		if err == nil && fmt.Sprint(elements) != fmt.Sprint(recovered) {
+			t.Fatalf("%q: recovered elements %v differ from %v", data, recovered, elements)
+		}
+		for i, e := range recovered[1:] {
+			if e.offset < 0 || e.offset >= len(text) || e.parent < 0 || e.parent > i {
+				t.Fatalf("%q: element %d out of bounds", data, i+1)
+			}
+		}
+	})
+}
+
+func FuzzStreamParser(f *testing.F) {
+	for _, c := range parseJSONCases {
+		f.Add(c.data, uint(len(c.data)/2), uint8(0))
+	}
+	f.Add([]byte("{a: 'b', // c\n d: [+.5, 0x1F, -Infinity],}"), uint(10), uint8(SyntaxJSON5))
+	f.Add([]byte("[1, /* c */ 2,]"), uint(7), uint8(SyntaxJSONC))
+	f.Fuzz(func(t *testing.T, data []byte, split uint, syntax uint8) {
+		opts := ParseOptions{Syntax: Syntax(syntax % 3)}
+		expected, expectedErr := parseJSONOptions(data, opts)
+		doc, err := parseChunks(data, opts, int(split%uint(len(data)+1)))
+		if (err == nil) != (expectedErr == nil) {
+			t.Fatalf("%q: error %v disagrees with %v", data, err, expectedErr)
+		}
+		if errors.Is(err, ErrUnexpectedEOF) && !errors.Is(expectedErr, ErrUnexpectedEOF) {
+			t.Fatalf("%q: error %v disagrees with %v", data, err, expectedErr)
+		}
+		if err == nil && fmt.Sprint(doc.elements) != fmt.Sprint(expected) {
+			t.Fatalf("%q: elements %v differ from %v", data, doc.elements, expected)
+		}
+	})
+}
+
+func FuzzParseJSONParallel(f *testing.F) {
+	for _, c := range parseJSONCases {
+		f.Add(c.data, uint8(3))
+	}
+	f.Add([]byte(`["a, [\"b\", {}]", "\\", "\\\"]"]`), uint8(7))
+	f.Fuzz(func(t *testing.T, data []byte, chunks uint8) {
+		expected, expectedErr := parseJSON(data)
+		elements, err := parseJSONParallel(data, ParseOptions{}, int(chunks%16))
+		if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+			t.Fatalf("%q: error %v differs from %v", data, err, expectedErr)
+		}
+		if fmt.Sprint(elements) != fmt.Sprint(expected) {
+			t.Fatalf("%q: elements %v differ from %v", data, elements, expected)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goInitialisms are words that are written in all caps in Go identifiers.
+var goInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "RAM": true, "SQL": true,
+	"SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true,
+	"UI": true, "UID": true, "URI": true, "URL": true, "UTF8": true,
+	"UUID": true, "XML": true,
+}
+
+// goGenerator holds the state while generating Go type declarations.
+type goGenerator struct {
+	decls []string        // type declarations in order of appearance
+	names map[string]bool // type names in use
+}
+
+// GenerateGo generates Go type declarations for the given sample documents.
+// The type of the root value gets the given name, nested objects are
+// declared as separate struct types. Members that are null or missing in
+// some samples become pointers with "omitempty".
+func GenerateGo(packageName, typeName string, docs ...*Document) ([]byte, error) {
+	var s SchemaInferrer
+	for _, doc := range docs {
+		s.Add(doc)
+	}
+
+	g := goGenerator{names: map[string]bool{}}
+	root := "interface{}"
+	if s.root != nil {
+		root = g.goType(s.root, typeName)
+	}
+	if root != typeName {
+		// the root is not a struct, so declare a named type for it
+		g.decls = append([]string{fmt.Sprintf("type %s %s\n", typeName, root)}, g.decls...)
+	}
+
+	src := fmt.Sprintf("package %s\n\n%s", packageName, strings.Join(g.decls, "\n"))
+	res, err := format.Source([]byte(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return res, nil
+}
+
+// goType returns the Go type for the summarized values. Objects are declared
+// as struct types with the given name.
+func (g *goGenerator) goType(t *typeSummary, name string) string {
+	// collect types except null, integers are subsumed by numbers
+	types := []string{}
+	for _, tpe := range typeOrder {
+		if t.types[tpe] && tpe != "null" && !(tpe == "integer" && t.types["number"]) {
+			types = append(types, tpe)
+		}
+	}
+	if len(types) != 1 {
+		// either only null or a mix of different types
+		return "interface{}"
+	}
+
+	switch types[0] {
+	case "boolean":
+		return "bool"
+	case "string":
+		return "string"
+	case "integer":
+		if fitsInt64(t.minimum) && fitsInt64(t.maximum) {
+			return "int64"
+		}
+		return "float64"
+	case "number":
+		return "float64"
+	case "array":
+		if t.items == nil {
+			return "[]interface{}"
+		}
+		return "[]" + g.goType(t.items, name+"Item")
+	case "object":
+		return g.declareStruct(t, name)
+	default:
+		return "interface{}"
+	}
+}
+
+// declareStruct declares a struct type for the summarized objects and returns
+// its name, which is made unique if necessary.
+func (g *goGenerator) declareStruct(t *typeSummary, name string) string {
+	name = uniqueName(name, g.names)
+	g.names[name] = true
+
+	// reserve a slot, so that the outer type precedes the nested ones
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	fields := map[string]bool{}
+	for _, member := range t.order {
+		p := t.properties[member]
+		if !isValidTagName(member) {
+			// encoding/json would use the field name instead
+			fmt.Fprintf(&b, "\t// member %s can't be named in a struct tag\n", strconv.Quote(member))
+			continue
+		}
+
+		field := uniqueName(goIdentifier(member), fields)
+		fields[field] = true
+
+		tpe := g.goType(p, name+field)
+		tag := member
+		optional := p.count < t.objects || p.types["null"]
+		if optional {
+			tag += ",omitempty"
+			if !strings.HasPrefix(tpe, "[]") && tpe != "interface{}" {
+				tpe = "*" + tpe
+			}
+		} else if member == "-" {
+			// a plain "-" skips the field
+			tag += ","
+		}
+		fmt.Fprintf(&b, "\t%s %s %s\n", field, tpe, goTag("json:"+strconv.Quote(tag)))
+	}
+	b.WriteString("}\n")
+
+	g.decls[slot] = b.String()
+	return name
+}
+
+// goIdentifier converts a JSON member name into an exported Go identifier.
+func goIdentifier(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	res := b.String()
+	if res == "" || !unicode.IsUpper([]rune(res)[0]) {
+		res = "Field" + res
+	}
+	return res
+}
+
+// uniqueName appends a number to the name if it is already in use.
+func uniqueName(name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := name + strconv.Itoa(i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// isValidTagName tells whether encoding/json accepts the member name in a
+// struct tag, which excludes commas, quotes and backslashes.
+func isValidTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", r) && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// goTag quotes a struct tag, using a raw string literal if possible.
+func goTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// fitsInt64 tells whether the integer fits into an int64.
+func fitsInt64(x *decimal) bool {
+	r, ok := x.rat()
+	return ok && r.IsInt() && r.Num().IsInt64()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+)
+
+type genGoTest struct {
+	samples []string
+	source  string
+}
+
+func TestGenerateGo(t *testing.T) {
+	cases := map[string]genGoTest{
+		"no samples": {
+			samples: []string{},
+			source:  "package main\n\ntype Root interface{}\n",
+		},
+		"scalar": {
+			samples: []string{`"a"`},
+			source:  "package main\n\ntype Root string\n",
+		},
+		"array": {
+			samples: []string{`[1, 2]`, `[]`},
+			source:  "package main\n\ntype Root []int64\n",
+		},
+		"mixed": {
+			samples: []string{`[1, "2"]`},
+			source:  "package main\n\ntype Root []interface{}\n",
+		},
+		"object": {
+			samples: []string{`{"id": 1, "user_name": "a", "score": 1.5, "ok": true, "big": 1e30}`},
+			source: "package main\n\n" +
+				"type Root struct {\n" +
+				"\tID       int64   `json:\"id\"`\n" +
+				"\tUserName string  `json:\"user_name\"`\n" +
+				"\tScore    float64 `json:\"score\"`\n" +
+				"\tOk       bool    `json:\"ok\"`\n" +
+				"\tBig      float64 `json:\"big\"`\n" +
+				"}\n",
+		},
+		"optional": {
+			samples: []string{`{"a": 1, "b": null, "c": [1]}`, `{"b": "x", "d": {"e": true}}`},
+			source: "package main\n\n" +
+				"type Root struct {\n" +
+				"\tA *int64  `json:\"a,omitempty\"`\n" +
+				"\tB *string `json:\"b,omitempty\"`\n" +
+				"\tC []int64 `json:\"c,omitempty\"`\n" +
+				"\tD *RootD  `json:\"d,omitempty\"`\n" +
+				"}\n\n" +
+				"type RootD struct {\n" +
+				"\tE bool `json:\"e\"`\n" +
+				"}\n",
+		},
+		"nested": {
+			samples: []string{`[{"items": [{"sku": "a"}], "2x": 1, "x2": 2, "X2": 3}]`},
+			source: "package main\n\n" +
+				"type Root []RootItem\n\n" +
+				"type RootItem struct {\n" +
+				"\tItems   []RootItemItemsItem `json:\"items\"`\n" +
+				"\tField2x int64               `json:\"2x\"`\n" +
+				"\tX2      int64               `json:\"x2\"`\n" +
+				"\tX22     int64               `json:\"X2\"`\n" +
+				"}\n\n" +
+				"type RootItemItemsItem struct {\n" +
+				"\tSku string `json:\"sku\"`\n" +
+				"}\n",
+		},
+		"tag names": {
+			samples: []string{`{"-": 1, "a,b": 2, "q\"": true, "": 3, "x y": 4}`, `{"-": 1, "x y": 5, "$ref": "r"}`},
+			source: "package main\n\n" +
+				"type Root struct {\n" +
+				"\tField int64 `json:\"-,\"`\n" +
+				"\t// member \"a,b\" can't be named in a struct tag\n" +
+				"\t// member \"q\\\"\" can't be named in a struct tag\n" +
+				"\t// member \"\" can't be named in a struct tag\n" +
+				"\tXY  int64   `json:\"x y\"`\n" +
+				"\tRef *string `json:\"$ref,omitempty\"`\n" +
+				"}\n",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			docs := []*Document{}
+			for _, s := range c.samples {
+				doc, err := ParseDocument([]byte(s))
+				if err != nil {
+					t.Fatal("failed to parse sample", err)
+				}
+				docs = append(docs, doc)
+			}
+
+			source, err := GenerateGo("main", "Root", docs...)
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			if string(source) != c.source {
+				t.Log("expected source\n" + c.source)
+				t.Log("received source\n" + string(source))
+				t.Error("wrong source")
+			}
+		})
+	}
+}
@@ -1 +1,3 @@
 module json-parser
+
+go 1.18
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"errors"
+)
+
+// states of the incremental parser, i.e. what it expects next
+const (
+	rsValue     = iota // a value, at the root level or after a colon
+	rsFirst            // the first element or member or a closing token
+	rsSeparator        // a comma or a closing token
+	rsNext             // an element or member after a comma
+	rsColon            // a colon after a member name
+	rsDone             // nothing, the root value is complete
+)
+
+// parserFrame is the saved state of an enclosing array or object.
+type parserFrame struct {
+	state   int // state after the nested value
+	start   int // start of the current element or member
+	members int // number of members, for objects
+}
+
+// incrementalParser builds the syntax tree token by token, validating the
+// structure on the fly. Unless strict, it recovers from errors as described
+// for ParseDocumentRecover.
+type incrementalParser struct {
+	data    []byte
+	opts    ParseOptions
+	strict  bool
+	res     []JSONElement
+	errs    []*ParseError
+	hints   []string      // expected tokens at each of the errors
+	context int           // index of the innermost open array or object
+	state   int           // expected token, one of the rs* constants
+	start   int           // index where the current element or member starts
+	members int           // number of members of the current object
+	stack   []parserFrame // state of the enclosing arrays and objects
+	// skipping tells whether tokens are skipped after an error, skipDepth
+	// counts the arrays and objects opened while skipping
+	skipping  bool
+	skipDepth int
+	// stopped tells whether the rest of the input is ignored
+	stopped bool
+}
+
+// newIncrementalParser returns a parser for the data, which stops at the
+// first error if strict.
+func newIncrementalParser(data []byte, opts ParseOptions, strict bool) *incrementalParser {
+	return &incrementalParser{
+		data:   data,
+		opts:   opts,
+		strict: strict,
+		res:    []JSONElement{{tpe: tRoot}},
+		state:  rsValue,
+		start:  1,
+	}
+}
+
+// report records an error, which stops a strict parser.
+func (p *incrementalParser) report(offset int, err error) {
+	p.errs = append(p.errs, &ParseError{Offset: offset, Err: err})
+	p.hints = append(p.hints, p.expected())
+	if p.strict {
+		p.stopped = true
+	}
+}
+
+// expected describes the tokens that the parser expects next, as a hint for
+// error messages.
+func (p *incrementalParser) expected() string {
+	closing, name := "']'", "a value"
+	if p.res[p.context].tpe == tObjectStart {
+		closing, name = "'}'", "a member name"
+	}
+	switch p.state {
+	case rsFirst:
+		return name + " or " + closing
+	case rsSeparator:
+		return "',' or " + closing
+	case rsNext:
+		if p.opts.Syntax.trailingCommas() {
+			return name + " or " + closing
+		}
+		return name
+	case rsColon:
+		return "':'"
+	case rsDone:
+		return "end of input"
+	default:
+		return "a value"
+	}
+}
+
+// tokenError records the error of the invalid token at the given offset.
+func (p *incrementalParser) tokenError(cur int, err error) {
+	var e *ParseError
+	if errors.As(err, &e) {
+		p.fail(e.Offset, e.Err)
+	} else {
+		p.fail(cur, err)
+	}
+}
+
+// fail records an error and starts skipping tokens, dropping the incomplete
+// element or member.
+func (p *incrementalParser) fail(offset int, err error) {
+	p.report(offset, err)
+	switch {
+	case p.stopped:
+		return
+	case p.context == 0:
+		if p.state == rsDone {
+			// there must be only a single value at the root level
+			p.stopped = true
+		}
+		return
+	case p.state == rsNext, p.state == rsColon, p.state == rsValue:
+		p.drop()
+	}
+	p.skipping = true
+	p.skipDepth = 0
+}
+
+// drop removes the current element or member including the preceding comma.
+func (p *incrementalParser) drop() {
+	if p.res[p.context].tpe == tObjectStart && p.state != rsNext {
+		// the member name was counted
+		p.members--
+	}
+	p.res = p.res[:p.start]
+	if p.start == p.context+1 {
+		p.state = rsFirst
+	} else {
+		p.state = rsSeparator
+	}
+}
+
+// token processes the next token of the input.
+func (p *incrementalParser) token(elem JSONElement) {
+	if p.stopped {
+		return
+	}
+	if p.skipping {
+		switch elem.tpe {
+		case tObjectStart, tArrayStart:
+			p.skipDepth++
+			return
+		case tObjectEnd, tArrayEnd:
+			if p.skipDepth > 0 {
+				p.skipDepth--
+				return
+			}
+		case tComma:
+			if p.skipDepth > 0 {
+				return
+			}
+			if p.state == rsFirst {
+				// the dropped element was the first one
+				p.skipping = false
+				return
+			}
+		default:
+			return
+		}
+		p.skipping = false
+	}
+
+	if p.opts.MaxElements > 0 && len(p.res) >= p.opts.MaxElements {
+		p.report(elem.offset, ErrTooManyElements)
+		p.stopped = true
+		return
+	}
+	if elem.tpe == tComment {
+		p.append(elem, p.context)
+		return
+	}
+
+	object := p.res[p.context].tpe == tObjectStart
+	name := object && (p.state == rsFirst || p.state == rsNext)
+	if p.opts.Syntax == SyntaxJSON5 && isIdentifierToken(p.data, elem) {
+		if name {
+			elem.tpe = tString
+		} else if elem.tpe == tString {
+			// identifiers are only allowed as member names
+			p.fail(elem.offset, ErrInvalidStructure)
+			return
+		}
+	}
+
+	switch elem.tpe {
+	case tObjectEnd, tArrayEnd:
+		p.close(elem)
+	case tComma:
+		switch {
+		case p.state == rsSeparator:
+			p.start = len(p.res)
+			p.append(elem, p.context)
+			p.state = rsNext
+		case p.context == 0:
+			p.fail(elem.offset, ErrInvalidStructure)
+		case p.state == rsColon, p.state == rsValue:
+			// drop the incomplete member, the comma separates the next one
+			p.fail(elem.offset, ErrInvalidStructure)
+			p.token(elem)
+		default:
+			// ignore the superfluous comma
+			p.report(elem.offset, ErrInvalidStructure)
+		}
+	case tColon:
+		if p.state != rsColon {
+			p.fail(elem.offset, ErrInvalidStructure)
+			return
+		}
+		if p.opts.MaxMembers > 0 && p.members > p.opts.MaxMembers {
+			p.fail(p.res[len(p.res)-1].offset, ErrTooManyMembers)
+			return
+		}
+		p.append(elem, p.context)
+		p.state = rsValue
+	default:
+		switch {
+		case name && elem.tpe == tString:
+			if p.state == rsFirst {
+				p.start = len(p.res)
+			}
+			p.members++
+			p.append(elem, p.context)
+			p.state = rsColon
+		case p.state == rsValue || (!object && (p.state == rsFirst || p.state == rsNext)):
+			if p.state == rsFirst {
+				p.start = len(p.res)
+			}
+			p.value(elem)
+		default:
+			p.fail(elem.offset, ErrInvalidStructure)
+			if p.skipping {
+				// the value may be an array or object to skip
+				p.token(elem)
+			}
+		}
+	}
+}
+
+// value processes a value in a valid position.
+func (p *incrementalParser) value(elem JSONElement) {
+	next := rsSeparator
+	if p.context == 0 {
+		next = rsDone
+	}
+	if elem.tpe != tObjectStart && elem.tpe != tArrayStart {
+		p.append(elem, p.context)
+		p.state = next
+		return
+	}
+
+	if depth := p.opts.maxDepth(); depth >= 0 && len(p.stack) >= depth {
+		p.fail(elem.offset, ErrTooDeep)
+		if p.skipping {
+			p.token(elem)
+		}
+		return
+	}
+	p.stack = append(p.stack, parserFrame{state: next, start: p.start, members: p.members})
+	p.append(elem, p.context)
+	p.context = len(p.res) - 1
+	p.state = rsFirst
+	p.start = len(p.res)
+	p.members = 0
+}
+
+// close processes a closing token.
+func (p *incrementalParser) close(elem JSONElement) {
+	expected := tArrayStart
+	if elem.tpe == tObjectEnd {
+		expected = tObjectStart
+	}
+	if p.res[p.context].tpe != expected {
+		// ignore the closing token, which doesn't match
+		p.report(elem.offset, ErrInvalidStructure)
+		return
+	}
+
+	switch p.state {
+	case rsNext:
+		if !p.opts.Syntax.trailingCommas() {
+			p.report(p.res[p.start].offset, ErrInvalidStructure)
+			p.drop()
+		}
+	case rsColon, rsValue:
+		p.report(elem.offset, ErrInvalidStructure)
+		p.drop()
+	}
+	if p.stopped {
+		return
+	}
+
+	parent := p.res[p.context].parent
+	p.append(elem, parent)
+	frame := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	p.context = parent
+	p.state = frame.state
+	p.start = frame.start
+	p.members = frame.members
+}
+
+// append adds an element to the syntax tree.
+func (p *incrementalParser) append(elem JSONElement, parent int) {
+	elem.parent = parent
+	p.res = append(p.res, elem)
+}
+
+// result returns the syntax tree of a strict parser that processed all tokens
+// of the input, or its first error. Invalid structure is reported as plain
+// ErrInvalidStructure and truncated input as ErrUnexpectedEOF together with
+// the elements parsed so far.
+func (p *incrementalParser) result() ([]JSONElement, error) {
+	if len(p.errs) > 0 {
+		if err := p.errs[0]; err.Err != ErrInvalidStructure {
+			return nil, err
+		}
+		return nil, ErrInvalidStructure
+	}
+	if p.context != 0 {
+		return p.res, ErrUnexpectedEOF
+	}
+	return applyDuplicateKeyPolicy(p.data, p.res, p.opts)
+}
+
+// open returns the indices of the open arrays and objects, innermost last.
+func (p *incrementalParser) open() []int {
+	res := []int{}
+	for i := p.context; i != 0; i = p.res[i].parent {
+		res = append([]int{i}, res...)
+	}
+	return res
+}
+
+// clone returns a copy of the parser that can be used independently.
+func (p *incrementalParser) clone() *incrementalParser {
+	res := *p
+	res.res = append([]JSONElement(nil), p.res...)
+	res.errs = append([]*ParseError(nil), p.errs...)
+	res.hints = append([]string(nil), p.hints...)
+	res.stack = append([]parserFrame(nil), p.stack...)
+	return &res
+}
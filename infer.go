@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// schemaDialect is the URI of the JSON Schema dialect emitted by InferSchema.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaInferrer infers a JSON Schema from sample documents. The zero value
+// is ready to use.
+type SchemaInferrer struct {
+	root *typeSummary
+}
+
+// typeSummary accumulates the observed values at one location of the samples.
+type typeSummary struct {
+	types      map[string]bool
+	minimum    *decimal
+	maximum    *decimal
+	objects    int                     // number of objects observed
+	count      int                     // number of values observed
+	properties map[string]*typeSummary // summaries of object members
+	order      []string                // member names in order of appearance
+	items      *typeSummary            // summary of array elements
+}
+
+// typeOrder defines the order of type names in the emitted schema.
+var typeOrder = []string{"null", "boolean", "integer", "number", "string", "array", "object"}
+
+// InferSchema infers a JSON Schema describing all of the given documents.
+func InferSchema(docs ...*Document) []byte {
+	var s SchemaInferrer
+	for _, doc := range docs {
+		s.Add(doc)
+	}
+	return s.Schema()
+}
+
+// ParseNDJSON parses newline-delimited JSON, i.e. one document per line.
+// Empty lines are skipped.
+func ParseNDJSON(data []byte) ([]*Document, error) {
+	res := []*Document{}
+	for n, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		doc, err := ParseDocument(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		res = append(res, doc)
+	}
+	return res, nil
+}
+
+// Add adds a sample document. Empty documents are ignored.
+func (s *SchemaInferrer) Add(doc *Document) {
+	root := doc.root()
+	if root == 0 {
+		return
+	}
+	if s.root == nil {
+		s.root = &typeSummary{}
+	}
+	s.root.add(doc, root)
+}
+
+// Schema returns the inferred schema as JSON document. Properties that are
+// present in all observed objects are listed as required. Numbers without
+// fractional part are typed as "integer", unless there are others, too. If
+// there were no samples, the schema accepts any value.
+func (s *SchemaInferrer) Schema() []byte {
+	buf := []byte{}
+	if s.root == nil {
+		buf = appendSchemaObject(buf, 0, []schemaMember{
+			{"$schema", appendString(nil, schemaDialect)},
+		})
+	} else {
+		buf = s.root.appendSchema(buf, 0, true)
+	}
+	return append(buf, '\n')
+}
+
+// add merges the value at the given index into the summary.
+func (t *typeSummary) add(doc *Document, index int) {
+	t.count++
+	if t.types == nil {
+		t.types = map[string]bool{}
+	}
+
+	switch doc.elements[index].tpe {
+	case tNull:
+		t.types["null"] = true
+	case tBool:
+		t.types["boolean"] = true
+	case tString:
+		t.types["string"] = true
+	case tNumber:
+		x, _ := parseDecimal(doc.numberText(index))
+		if x.isInteger() {
+			t.types["integer"] = true
+		} else {
+			t.types["number"] = true
+		}
+		if t.minimum == nil || compareDecimal(x, *t.minimum) < 0 {
+			t.minimum = &x
+		}
+		if t.maximum == nil || compareDecimal(x, *t.maximum) > 0 {
+			t.maximum = &x
+		}
+	case tArrayStart:
+		t.types["array"] = true
+		for _, item := range doc.children(index) {
+			if t.items == nil {
+				t.items = &typeSummary{}
+			}
+			t.items.add(doc, item)
+		}
+	case tObjectStart:
+		t.types["object"] = true
+		t.objects++
+		if t.properties == nil {
+			t.properties = map[string]*typeSummary{}
+		}
+		children := doc.children(index)
+		seen := make(map[string]bool, len(children)/2)
+		for i := 0; i+1 < len(children); i += 2 {
+			name, err := doc.stringValue(children[i])
+			if err != nil || seen[name] {
+				continue
+			}
+			seen[name] = true
+			p, ok := t.properties[name]
+			if !ok {
+				p = &typeSummary{}
+				t.properties[name] = p
+				t.order = append(t.order, name)
+			}
+			p.add(doc, children[i+1])
+		}
+	}
+}
+
+// schemaMember is a member of an object in the emitted schema, the value is
+// already encoded.
+type schemaMember struct {
+	name  string
+	value []byte
+}
+
+// appendSchema appends the schema for the summary to the buffer. The depth
+// determines the indentation.
+func (t *typeSummary) appendSchema(buf []byte, depth int, root bool) []byte {
+	members := []schemaMember{}
+	if root {
+		members = append(members, schemaMember{"$schema", appendString(nil, schemaDialect)})
+	}
+
+	// integers are numbers, too, so only list the more general type
+	types := []string{}
+	for _, name := range typeOrder {
+		if t.types[name] && !(name == "integer" && t.types["number"]) {
+			types = append(types, name)
+		}
+	}
+	if len(types) == 1 {
+		members = append(members, schemaMember{"type", appendString(nil, types[0])})
+	} else {
+		members = append(members, schemaMember{"type", appendStringArray(nil, types)})
+	}
+
+	if t.minimum != nil {
+		members = append(members,
+			schemaMember{"minimum", []byte(t.minimum.plain())},
+			schemaMember{"maximum", []byte(t.maximum.plain())})
+	}
+
+	if t.items != nil {
+		members = append(members, schemaMember{"items", t.items.appendSchema(nil, depth+1, false)})
+	}
+
+	if t.objects != 0 {
+		props := []schemaMember{}
+		required := []string{}
+		for _, name := range t.order {
+			p := t.properties[name]
+			props = append(props, schemaMember{name, p.appendSchema(nil, depth+2, false)})
+			if p.count == t.objects {
+				required = append(required, name)
+			}
+		}
+		members = append(members, schemaMember{"properties", appendSchemaObject(nil, depth+1, props)})
+		if len(required) != 0 {
+			members = append(members, schemaMember{"required", appendStringArray(nil, required)})
+		}
+	}
+
+	return appendSchemaObject(buf, depth, members)
+}
+
+// appendSchemaObject appends an object with one member per line.
+func appendSchemaObject(buf []byte, depth int, members []schemaMember) []byte {
+	if len(members) == 0 {
+		return append(buf, '{', '}')
+	}
+	buf = append(buf, '{')
+	for i, m := range members {
+		if i != 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '\n')
+		buf = append(buf, bytes.Repeat([]byte("  "), depth+1)...)
+		buf = appendString(buf, m.name)
+		buf = append(buf, ':', ' ')
+		buf = append(buf, m.value...)
+	}
+	buf = append(buf, '\n')
+	buf = append(buf, bytes.Repeat([]byte("  "), depth)...)
+	return append(buf, '}')
+}
+
+// appendStringArray appends an array of strings on a single line.
+func appendStringArray(buf []byte, values []string) []byte {
+	buf = append(buf, '[')
+	for i, v := range values {
+		if i != 0 {
+			buf = append(buf, ',', ' ')
+		}
+		buf = appendString(buf, v)
+	}
+	return append(buf, ']')
+}
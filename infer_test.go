@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+)
+
+type inferTest struct {
+	samples []string
+	schema  string
+}
+
+func TestInferSchema(t *testing.T) {
+	cases := map[string]inferTest{
+		"no samples": {
+			samples: []string{},
+			schema:  `{"$schema": "https://json-schema.org/draft/2020-12/schema"}`,
+		},
+		"scalar": {
+			samples: []string{`"a"`},
+			schema:  `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "string"}`,
+		},
+		"union": {
+			samples: []string{`"a"`, `null`, `true`},
+			schema:  `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": ["null", "boolean", "string"]}`,
+		},
+		"integers": {
+			samples: []string{`3`, `-2`, `10e1`},
+			schema:  `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "integer", "minimum": -2, "maximum": 100}`,
+		},
+		"numbers": {
+			samples: []string{`3`, `-2.5`},
+			schema:  `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "number", "minimum": -2.5, "maximum": 3}`,
+		},
+		"array": {
+			samples: []string{`[]`, `["a", 1]`},
+			schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "array",
+				"items": {"type": ["integer", "string"], "minimum": 1, "maximum": 1}}`,
+		},
+		"objects": {
+			samples: []string{`{"a": 1, "b": {"x": null}}`, `{"b": {"x": "y", "z": []}, "a": 2, "c": true}`},
+			schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object",
+				"properties": {
+					"a": {"type": "integer", "minimum": 1, "maximum": 2},
+					"b": {"type": "object", "properties": {"x": {"type": ["null", "string"]}, "z": {"type": "array"}}, "required": ["x"]},
+					"c": {"type": "boolean"}
+				},
+				"required": ["a", "b"]}`,
+		},
+		"escaped names": {
+			samples: []string{`{"a\"b": 1}`},
+			schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object",
+				"properties": {"a\"b": {"type": "integer", "minimum": 1, "maximum": 1}}, "required": ["a\"b"]}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			docs := []*Document{}
+			for _, s := range c.samples {
+				doc, err := ParseDocument([]byte(s))
+				if err != nil {
+					t.Fatal("failed to parse sample", err)
+				}
+				docs = append(docs, doc)
+			}
+
+			schema := InferSchema(docs...)
+			equal, err := Equal(schema, []byte(c.schema))
+			if err != nil {
+				t.Log("schema", string(schema))
+				t.Fatal("failed to compare", err)
+			}
+			if !equal {
+				t.Log("expected schema", c.schema)
+				t.Log("received schema", string(schema))
+				t.Fatal("wrong schema")
+			}
+
+			// the samples must be valid according to the inferred schema
+			compiled, err := CompileSchema(schema)
+			if err != nil {
+				t.Fatal("failed to compile schema", err)
+			}
+			for _, doc := range docs {
+				if err := compiled.ValidateDocument(doc); err != nil {
+					t.Error("sample doesn't validate", err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseNDJSON(t *testing.T) {
+	docs, err := ParseNDJSON([]byte("{\"a\": 1}\n\n[2]\r\n  \n\"3\""))
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 documents, received %d", len(docs))
+	}
+
+	if _, err := ParseNDJSON([]byte("1\n[1,]\n")); err == nil {
+		t.Error("expected error missing")
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// nextJSON5Token handles the tokens at the given offset that only exist in
+// JSON5. It returns the type and size of the token, where the type is tNone
+// for whitespace and comments. If the size is zero, the token is the same as
+// in JSON.
+func nextJSON5Token(data []byte, cur int, opts ParseOptions) (int, int, error) {
+	length := len(data)
+	c := data[cur]
+	switch {
+	case c == '/':
+		size, err := findEndOfComment(data, cur, length)
+		return tNone, size, err
+	case c == '\'':
+		size, err := stringToken(data, cur, opts)
+		return tString, size, err
+	case c == '+' || c == '.':
+		size, err := numberToken(data, cur, opts)
+		return tNumber, size, err
+	case c == '\v' || c == '\f':
+		return tNone, 1, nil
+	}
+
+	r, size := utf8.DecodeRune(data[cur:])
+	if isJSON5Space(r) {
+		return tNone, size, nil
+	}
+	if c != '\\' && !isIdentifierStart(r) {
+		return tNone, 0, nil
+	}
+
+	size, err := findEndOfIdentifier(data, cur, length)
+	if err != nil {
+		return tNone, 0, err
+	}
+	switch string(data[cur : cur+size]) {
+	case "true", "false":
+		return tBool, size, nil
+	case "null":
+		return tNull, size, nil
+	case "Infinity", "NaN":
+		return tNumber, size, nil
+	default:
+		// only valid as member name, which the parser checks
+		return tString, size, nil
+	}
+}
+
+// isJSON5Space tells whether the character is whitespace in JSON5.
+func isJSON5Space(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', ' ', 0xa0, 0x2028, 0x2029, 0xfeff:
+		return true
+	default:
+		return unicode.Is(unicode.Zs, r)
+	}
+}
+
+// isIdentifierStart tells whether the character can start an ECMAScript 5.1
+// identifier.
+func isIdentifierStart(r rune) bool {
+	return r == '$' || r == '_' || unicode.In(r, unicode.Lu, unicode.Ll, unicode.Lt, unicode.Lm, unicode.Lo, unicode.Nl)
+}
+
+// isIdentifierPart tells whether the character can continue an ECMAScript 5.1
+// identifier.
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) || r == 0x200c || r == 0x200d
+}
+
+// isIdentifierToken tells whether the element is a JSON5 token that consists
+// of an identifier, which includes "true", "null" and "Infinity".
+func isIdentifierToken(data []byte, elem JSONElement) bool {
+	c := data[elem.offset]
+	switch elem.tpe {
+	case tString:
+		return c != '"' && c != '\''
+	case tBool, tNull:
+		return true
+	case tNumber:
+		return c == 'I' || c == 'N'
+	default:
+		return false
+	}
+}
+
+// findEndOfIdentifier returns the size of the identifier at the given offset.
+// Identifiers may contain Unicode escape sequences like "\u0041".
+func findEndOfIdentifier(data []byte, cur, length int) (int, error) {
+	res := 0
+	for cur+res != length {
+		var r rune
+		size := 0
+		escaped := data[cur+res] == '\\'
+		if escaped {
+			if cur+res+1 == length || data[cur+res+1] != 'u' || !isHex(data[cur+res+2:length], 4) {
+				if rest := data[cur+res+1 : length]; len(rest) == 0 || (rest[0] == 'u' && len(rest) < 5 && isHex(rest[1:], len(rest)-1)) {
+					// escape sequence truncated by the end of the data
+					return 0, ErrUnexpectedEOF
+				}
+				return 0, ErrInvalidToken
+			}
+			r, _ = decodeHex4(data[cur+res+2:])
+			size = 6
+		} else {
+			r, size = utf8.DecodeRune(data[cur+res : length])
+		}
+
+		valid := isIdentifierPart(r)
+		if res == 0 {
+			valid = isIdentifierStart(r)
+		}
+		if !valid {
+			if escaped {
+				// escape sequences must not end the identifier
+				return 0, ErrInvalidToken
+			}
+			break
+		}
+		res += size
+	}
+	if res == 0 {
+		return 0, ErrInvalidToken
+	}
+	return res, nil
+}
+
+// findEndOfComment returns the size of the comment at the given offset. The
+// line terminator after a single-line comment isn't part of the comment.
+func findEndOfComment(data []byte, cur, length int) (int, error) {
+	if cur+1 == length {
+		return 0, ErrUnexpectedEOF
+	}
+	switch data[cur+1] {
+	case '/':
+		res := 2
+		for cur+res != length {
+			if c := data[cur+res]; c == '\n' || c == '\r' {
+				break
+			}
+			if r, _ := utf8.DecodeRune(data[cur+res : length]); r == 0x2028 || r == 0x2029 {
+				break
+			}
+			res++
+		}
+		return res, nil
+	case '*':
+		end := bytes.Index(data[cur+2:length], []byte("*/"))
+		if end < 0 {
+			// unterminated comment
+			return 0, ErrUnexpectedEOF
+		}
+		return end + 4, nil
+	default:
+		return 0, ErrInvalidToken
+	}
+}
+
+// json5Escape returns the size of the JSON5 escape sequence at the start of
+// the data, which follows the backslash. Escape sequences that also exist in
+// JSON are handled by findMatchingQuotes.
+func json5Escape(data []byte) (int, error) {
+	switch c := data[0]; {
+	case c == '0':
+		// null character, which must not be followed by a digit
+		if len(data) > 1 && data[1] >= '0' && data[1] <= '9' {
+			return 0, ErrInvalidToken
+		}
+		return 1, nil
+	case c >= '1' && c <= '9':
+		// octal escape sequences are not allowed
+		return 0, ErrInvalidToken
+	case c == 'x':
+		if !isHex(data[1:], 2) {
+			if len(data) < 3 && isHex(data[1:], len(data)-1) {
+				return 0, ErrUnexpectedEOF
+			}
+			return 0, ErrInvalidToken
+		}
+		return 3, nil
+	case c == '\r' && len(data) > 1 && data[1] == '\n':
+		// line continuation
+		return 2, nil
+	default:
+		// any other character stands for itself, line terminators are
+		// removed as line continuation
+		return 1, nil
+	}
+}
+
+// decodeIdentifier decodes the escape sequences of an identifier token.
+func decodeIdentifier(token []byte) string {
+	if bytes.IndexByte(token, '\\') < 0 {
+		return string(token)
+	}
+	var b strings.Builder
+	for i := 0; i < len(token); i++ {
+		if token[i] != '\\' {
+			b.WriteByte(token[i])
+			continue
+		}
+		r, _ := decodeHex4(token[i+2:])
+		b.WriteRune(r)
+		i += 5
+	}
+	return b.String()
+}
+
+// normalizeJSON5Number converts a JSON5 number token into a JSON number.
+// Infinity and NaN can't be converted and only lose a leading plus sign.
+func normalizeJSON5Number(token []byte) []byte {
+	s := token
+	neg := false
+	switch s[0] {
+	case '+':
+		s = s[1:]
+	case '-':
+		neg = true
+		s = s[1:]
+	}
+
+	res := []byte{}
+	if neg {
+		res = append(res, '-')
+	}
+	if len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		n, _ := new(big.Int).SetString(string(s[2:]), 16)
+		return n.Append(res, 10)
+	}
+	if s[0] == '.' {
+		// leading radix separator
+		res = append(res, '0')
+	}
+	if i := bytes.IndexByte(s, '.'); i >= 0 && (i+1 == len(s) || s[i+1] == 'e' || s[i+1] == 'E') {
+		// trailing radix separator
+		res = append(res, s[:i]...)
+		return append(res, s[i+1:]...)
+	}
+	return append(res, s...)
+}
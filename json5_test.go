@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+type json5Test struct {
+	data     string
+	expected string // equivalent JSON document, if accepted
+	err      error  // expected error, nil if none
+}
+
+func TestParseJSON5(t *testing.T) {
+	cases := map[string]json5Test{
+		"line comment":          {data: "// config\n[1, // one\n2]", expected: `[1, 2]`},
+		"block comment":         {data: "/* a\n * b */ {/**/\"a\"/* c */: 1}", expected: `{"a": 1}`},
+		"comment at end":        {data: "1 // end", expected: `1`},
+		"trailing comma array":  {data: `[1, 2,]`, expected: `[1, 2]`},
+		"trailing comma object": {data: `{"a": 1, "b": [2,],}`, expected: `{"a": 1, "b": [2]}`},
+		"single quotes":         {data: `['a"b', 'c\'d']`, expected: `["a\"b", "c'd"]`},
+		"identifier keys":       {data: `{a: 1, $b_2: 2, ünï: 3, ab: 4}`, expected: `{"a": 1, "$b_2": 2, "ünï": 3, "ab": 4}`},
+		"keyword keys":          {data: `{null: 1, true: 2, Infinity: 3}`, expected: `{"null": 1, "true": 2, "Infinity": 3}`},
+		"hexadecimal":           {data: `[0x1F, -0XFF, +0x0, 0xffffffffffffffffff]`, expected: `[31, -255, 0, 4722366482869645213695]`},
+		"decimal points":        {data: `[.5, 5., -.5e1, +5.E2]`, expected: `[0.5, 5, -0.5e1, 5E2]`},
+		"plus sign":             {data: `[+1, +0.5]`, expected: `[1, 0.5]`},
+		"escapes":               {data: `'\x41\v\0\q\/'`, expected: `"A\u000b\u0000q/"`},
+		"line continuation":     {data: "'a\\\nb\\\r\nc\\\rd\\ e'", expected: `"abcde"`},
+		"whitespace":            {data: "\v\f\u00a0\ufeff\u2028\u3000[1]", expected: `[1]`},
+		"identifier value": {
+			data: `{a: b}`,
+			err:  ErrInvalidStructure,
+		},
+		"identifier in array": {
+			data: `[a]`,
+			err:  ErrInvalidStructure,
+		},
+		"octal escape": {
+			data: `'\1'`,
+			err:  ErrInvalidToken,
+		},
+		"unterminated comment": {
+			data: `[1] /* end`,
+			err:  ErrUnexpectedEOF,
+		},
+		"exponent without digits": {
+			data: `.e3`,
+			err:  ErrInvalidToken,
+		},
+		"raw line break": {
+			data: "'a\nb'",
+			err:  ErrInvalidToken,
+		},
+		"double comma": {
+			data: `[1,,]`,
+			err:  ErrInvalidStructure,
+		},
+		"comma only": {
+			data: `[,]`,
+			err:  ErrInvalidStructure,
+		},
+	}
+
+	opts := ParseOptions{Syntax: SyntaxJSON5}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseDocumentOptions([]byte(c.data), opts)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Errorf("expected error %v, received %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			expected, err := ParseDocument([]byte(c.expected))
+			if err != nil {
+				t.Fatal("invalid expected document", err)
+			}
+			if !EqualDocuments(doc, expected) {
+				t.Errorf("expected %s, received %s", c.expected, c.data)
+			}
+			if _, err := ParseDocument([]byte(c.data)); err == nil {
+				t.Error("JSON5 syntax accepted in JSON mode")
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON5(t *testing.T) {
+	doc, err := ParseDocumentOptions([]byte(`[Infinity, -Infinity, +Infinity, NaN]`), ParseOptions{Syntax: SyntaxJSON5})
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	var v []float64
+	if err := UnmarshalDocument(doc, &v); err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	if len(v) != 4 || !math.IsInf(v[0], 1) || !math.IsInf(v[1], -1) || !math.IsInf(v[2], 1) || !math.IsNaN(v[3]) {
+		t.Errorf("expected [+Inf -Inf +Inf NaN], received %v", v)
+	}
+}
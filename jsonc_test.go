@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseJSONC(t *testing.T) {
+	cases := map[string]json5Test{
+		"line comment":          {data: "// settings\n{\"a\": 1 // one\n}", expected: `{"a": 1}`},
+		"block comment":         {data: `[/* a */ 1, /**/ 2 /* b */]`, expected: `[1, 2]`},
+		"comment only root":     {data: "/* a */ 1 // b", expected: `1`},
+		"comment between names": {data: `{"a" /* a */ : /* b */ 1}`, expected: `{"a": 1}`},
+		"trailing comma array":  {data: `[1, 2, /* end */]`, expected: `[1, 2]`},
+		"trailing comma object": {data: "{\"a\": [1,],\n}", expected: `{"a": [1]}`},
+		"unterminated comment": {
+			data: `[1] /* end`,
+			err:  ErrUnexpectedEOF,
+		},
+		"single slash": {
+			data: `[1] / 2`,
+			err:  ErrInvalidToken,
+		},
+		"two values": {
+			data: `1 /* a */ 2`,
+			err:  ErrInvalidStructure,
+		},
+		"comment as value": {
+			data: `{"a": /* b */}`,
+			err:  ErrInvalidStructure,
+		},
+		"single quotes": {
+			data: `['a']`,
+			err:  ErrInvalidToken,
+		},
+		"comma only": {
+			data: `[,]`,
+			err:  ErrInvalidStructure,
+		},
+		"tab in string": {
+			data: "[\"a\tb\"]",
+			err:  ErrInvalidToken,
+		},
+		"control byte in string": {
+			data: "[\"a\x01b\"]",
+			err:  ErrInvalidToken,
+		},
+		"control byte in comment": {data: "[1 /* \t\x01 */]", expected: `[1]`},
+	}
+
+	opts := ParseOptions{Syntax: SyntaxJSONC}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseDocumentOptions([]byte(c.data), opts)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Errorf("expected error %v, received %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			expected, err := ParseDocument([]byte(c.expected))
+			if err != nil {
+				t.Fatal("invalid expected document", err)
+			}
+			if !EqualDocuments(doc, expected) {
+				t.Errorf("expected %s, received %s", c.expected, c.data)
+			}
+			if _, err := ParseDocument([]byte(c.data)); err == nil {
+				t.Error("JSONC syntax accepted in JSON mode")
+			}
+		})
+	}
+}
+
+func TestDuplicateKeysJSONC(t *testing.T) {
+	opts := ParseOptions{Syntax: SyntaxJSONC, DuplicateKeys: DuplicateKeysKeepLast}
+	doc, err := ParseDocumentOptions([]byte(`{"a": 1, /* b */ "a": 2 /* c */,}`), opts)
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	expected := []int{tRoot, tObjectStart, tComment, tString, tColon, tNumber, tComment, tComma, tObjectEnd}
+	received := []int{}
+	for _, e := range doc.elements {
+		received = append(received, e.tpe)
+	}
+	if fmt.Sprint(received) != fmt.Sprint(expected) {
+		t.Errorf("expected element types %v, received %v", expected, received)
+	}
+}
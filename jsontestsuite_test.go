@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// jsonTestSuiteDir contains the test files of JSONTestSuite.
+const jsonTestSuiteDir = "testdata/JSONTestSuite/test_parsing"
+
+// jsonTestSuiteDeviations are "n_" files that the parsers may accept on
+// purpose: empty input and input consisting only of whitespace or a byte
+// order mark is a document without value.
+var jsonTestSuiteDeviations = map[string]bool{
+	"n_single_space.json":               true,
+	"n_structure_no_data.json":          true,
+	"n_structure_UTF8_BOM_no_data.json": true,
+}
+
+// jsonTestSuiteIndeterminate records whether ParseDocument accepts the "i_"
+// files, where the specification leaves the choice to the parser.
+var jsonTestSuiteIndeterminate = map[string]bool{
+	// numbers are not converted, so their range doesn't matter
+	"i_number_double_huge_neg_exp.json":   true,
+	"i_number_huge_exp.json":              true,
+	"i_number_neg_int_huge_exp.json":      true,
+	"i_number_pos_double_huge_exp.json":   true,
+	"i_number_real_neg_overflow.json":     true,
+	"i_number_real_pos_overflow.json":     true,
+	"i_number_real_underflow.json":        true,
+	"i_number_too_big_neg_int.json":       true,
+	"i_number_too_big_pos_int.json":       true,
+	"i_number_very_big_negative_int.json": true,
+	// unpaired surrogates in escapes are rejected by default
+	"i_object_key_lone_2nd_surrogate.json":                false,
+	"i_string_1st_surrogate_but_2nd_missing.json":         false,
+	"i_string_1st_valid_surrogate_2nd_invalid.json":       false,
+	"i_string_incomplete_surrogate_and_escape_valid.json": false,
+	"i_string_incomplete_surrogate_pair.json":             false,
+	"i_string_incomplete_surrogates_escape_valid.json":    false,
+	"i_string_invalid_lonely_surrogate.json":              false,
+	"i_string_invalid_surrogate.json":                     false,
+	"i_string_inverted_surrogates_U+1D11E.json":           false,
+	"i_string_lone_second_surrogate.json":                 false,
+	// strings must be valid UTF-8 by default
+	"i_string_UTF-8_invalid_sequence.json":         false,
+	"i_string_UTF8_surrogate_U+D800.json":          false,
+	"i_string_invalid_utf-8.json":                  false,
+	"i_string_iso_latin_1.json":                    false,
+	"i_string_lone_utf8_continuation_byte.json":    false,
+	"i_string_not_in_unicode_range.json":           false,
+	"i_string_overlong_sequence_2_bytes.json":      false,
+	"i_string_overlong_sequence_6_bytes.json":      false,
+	"i_string_overlong_sequence_6_bytes_null.json": false,
+	"i_string_truncated-utf-8.json":                false,
+	// the encoding is detected and a byte order mark is skipped
+	"i_string_UTF-16LE_with_BOM.json":         true,
+	"i_string_utf16BE_no_BOM.json":            true,
+	"i_string_utf16LE_no_BOM.json":            true,
+	"i_structure_UTF-8_BOM_empty_object.json": true,
+	// the nesting depth is within the default limit
+	"i_structure_500_nested_arrays.json": true,
+}
+
+// jsonTestSuiteDecoded are "i_" files that ParseDocument only accepts after
+// decoding the input, parseJSON expects UTF-8 without a byte order mark.
+var jsonTestSuiteDecoded = map[string]bool{
+	"i_string_UTF-16LE_with_BOM.json":         true,
+	"i_string_utf16BE_no_BOM.json":            true,
+	"i_string_utf16LE_no_BOM.json":            true,
+	"i_structure_UTF-8_BOM_empty_object.json": true,
+}
+
+func TestJSONTestSuite(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(jsonTestSuiteDir, "*.json"))
+	if err != nil {
+		t.Fatal("failed to list test files", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files found in", jsonTestSuiteDir)
+	}
+
+	parsers := map[string]func([]byte) error{
+		"parseJSON": func(data []byte) error {
+			_, err := parseJSON(data)
+			return err
+		},
+		"ParseDocument": func(data []byte) error {
+			_, err := ParseDocument(data)
+			return err
+		},
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal("failed to read test file", err)
+		}
+		for parserName, parse := range parsers {
+			t.Run(name+"/"+parserName, func(t *testing.T) {
+				err := parse(data)
+
+				switch {
+				case strings.HasPrefix(name, "y_"):
+					if err != nil {
+						t.Error("rejected valid input", err)
+					}
+				case strings.HasPrefix(name, "n_"):
+					if err == nil && !jsonTestSuiteDeviations[name] {
+						t.Error("accepted invalid input")
+					}
+				case strings.HasPrefix(name, "i_"):
+					accept, ok := jsonTestSuiteIndeterminate[name]
+					if !ok {
+						t.Fatal("no behavior recorded, result is", err)
+					}
+					if parserName == "parseJSON" && jsonTestSuiteDecoded[name] {
+						accept = false
+					}
+					if accept && err != nil {
+						t.Error("rejected input", err)
+					}
+					if !accept && err == nil {
+						t.Error("accepted input")
+					}
+				default:
+					t.Fatal("unexpected test file name")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// ColumnUnit is the unit in which a LineIndex counts columns.
+type ColumnUnit int
+
+const (
+	// ColumnBytes counts columns in bytes.
+	ColumnBytes ColumnUnit = iota
+	// ColumnRunes counts columns in Unicode code points, e.g. for terminals.
+	ColumnRunes
+	// ColumnUTF16 counts columns in UTF-16 code units, e.g. for editors
+	// speaking the Language Server Protocol.
+	ColumnUTF16
+)
+
+// LineIndex maps byte offsets of an input to line and column positions and
+// back. Lines are separated by '\n' and, like columns, counted from 1. Bytes
+// of invalid UTF-8 count as a single rune.
+type LineIndex struct {
+	data     []byte
+	newlines []int // offsets of the '\n' characters
+	unit     ColumnUnit
+}
+
+// NewLineIndex returns the line index of the data with columns counted in the
+// given unit.
+func NewLineIndex(data []byte, unit ColumnUnit) *LineIndex {
+	l := &LineIndex{data: data, unit: unit}
+	for i, c := range data {
+		if c == '\n' {
+			l.newlines = append(l.newlines, i)
+		}
+	}
+	return l
+}
+
+// Lines returns the number of lines.
+func (l *LineIndex) Lines() int {
+	return len(l.newlines) + 1
+}
+
+// Line returns the content of the given line without the '\n' ending it.
+func (l *LineIndex) Line(line int) []byte {
+	if line < 1 || line > l.Lines() {
+		return nil
+	}
+	return l.data[l.lineStart(line):l.lineEnd(line)]
+}
+
+// lineStart returns the offset of the first byte of the given line.
+func (l *LineIndex) lineStart(line int) int {
+	if line == 1 {
+		return 0
+	}
+	return l.newlines[line-2] + 1
+}
+
+// lineEnd returns the offset of the '\n' ending the given line, or the length
+// of the data for the last line.
+func (l *LineIndex) lineEnd(line int) int {
+	if line > len(l.newlines) {
+		return len(l.data)
+	}
+	return l.newlines[line-1]
+}
+
+// Position returns the line and column of the given offset. Offsets beyond
+// the data are clamped to its end.
+func (l *LineIndex) Position(offset int) (line, col int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(l.data) {
+		offset = len(l.data)
+	}
+	line = sort.SearchInts(l.newlines, offset) + 1
+	start := l.lineStart(line)
+	if l.unit == ColumnBytes {
+		return line, offset - start + 1
+	}
+	col = 1
+	for cur := start; cur < offset; {
+		r, size := utf8.DecodeRune(l.data[cur:])
+		col += l.width(r)
+		cur += size
+	}
+	return line, col
+}
+
+// Offset returns the offset of the given line and column. It fails if the
+// position is outside the data or in the middle of a character. The column
+// after the last character of a line is valid.
+func (l *LineIndex) Offset(line, col int) (int, bool) {
+	if line < 1 || line > l.Lines() || col < 1 {
+		return 0, false
+	}
+	cur, end := l.lineStart(line), l.lineEnd(line)
+	if l.unit == ColumnBytes {
+		return cur + col - 1, cur+col-1 <= end
+	}
+	for col > 1 {
+		if cur >= end {
+			return 0, false
+		}
+		r, size := utf8.DecodeRune(l.data[cur:])
+		col -= l.width(r)
+		cur += size
+	}
+	return cur, col == 1
+}
+
+// width returns the number of columns taken by the rune.
+func (l *LineIndex) width(r rune) int {
+	if l.unit == ColumnUTF16 && r >= 0x10000 {
+		// surrogate pair
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLineIndex(t *testing.T) {
+	data := []byte("{\n  \"äb\": \"😀x\",\n\n  \"c\": 1}")
+	cases := map[string]struct {
+		offset int
+		unit   ColumnUnit
+		line   int
+		col    int
+	}{
+		"start":             {offset: 0, unit: ColumnBytes, line: 1, col: 1},
+		"newline":           {offset: 1, unit: ColumnBytes, line: 1, col: 2},
+		"second line":       {offset: 2, unit: ColumnBytes, line: 2, col: 1},
+		"bytes":             {offset: 7, unit: ColumnBytes, line: 2, col: 6},
+		"runes":             {offset: 7, unit: ColumnRunes, line: 2, col: 5},
+		"utf16":             {offset: 7, unit: ColumnUTF16, line: 2, col: 5},
+		"after emoji bytes": {offset: 16, unit: ColumnBytes, line: 2, col: 15},
+		"after emoji runes": {offset: 16, unit: ColumnRunes, line: 2, col: 11},
+		"after emoji utf16": {offset: 16, unit: ColumnUTF16, line: 2, col: 12},
+		"empty line":        {offset: 20, unit: ColumnRunes, line: 3, col: 1},
+		"last line":         {offset: 23, unit: ColumnUTF16, line: 4, col: 3},
+		"end":               {offset: len(data), unit: ColumnRunes, line: 4, col: 10},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			l := NewLineIndex(data, c.unit)
+			line, col := l.Position(c.offset)
+			if line != c.line || col != c.col {
+				t.Errorf("expected %d:%d, received %d:%d", c.line, c.col, line, col)
+			}
+			offset, ok := l.Offset(c.line, c.col)
+			if !ok || offset != c.offset {
+				t.Errorf("expected offset %d, received %d (%v)", c.offset, offset, ok)
+			}
+		})
+	}
+}
+
+func TestLineIndexInvalidPosition(t *testing.T) {
+	data := []byte("[\"😀\",\n1]")
+	cases := map[string]struct {
+		line int
+		col  int
+		unit ColumnUnit
+	}{
+		"line zero":      {line: 0, col: 1},
+		"line too large": {line: 3, col: 1},
+		"column zero":    {line: 1, col: 0},
+		"beyond line":    {line: 1, col: 10, unit: ColumnBytes},
+		"beyond last":    {line: 2, col: 4, unit: ColumnRunes},
+		"surrogate pair": {line: 1, col: 4, unit: ColumnUTF16},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			l := NewLineIndex(data, c.unit)
+			if offset, ok := l.Offset(c.line, c.col); ok {
+				t.Errorf("expected invalid position, received offset %d", offset)
+			}
+		})
+	}
+
+	l := NewLineIndex(data, ColumnUTF16)
+	line, col := l.Position(100)
+	if fmt.Sprint(line, col) != "2 3" {
+		t.Errorf("expected clamped position 2:3, received %d:%d", line, col)
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 const (
@@ -19,6 +25,7 @@ const (
 	tNull
 	tNumber
 	tBool
+	tComment
 )
 
 // ErrInvalidToken signals that something could not be converted to a token.
@@ -30,6 +37,134 @@ var ErrInvalidToken = errors.New("invalid token")
 // and colons anywhere but as a separator between key and value of an object value.
 var ErrInvalidStructure = errors.New("invalid structure")
 
+// ErrUnexpectedEOF signals input that ends in the middle of the document,
+// i.e. inside an array, an object or a token.
+var ErrUnexpectedEOF = errors.New("unexpected end of input")
+
+// ErrInvalidUTF8 signals a string that isn't valid UTF-8.
+var ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+// ErrLoneSurrogate signals an escape sequence for a UTF-16 surrogate that is
+// not part of a surrogate pair.
+var ErrLoneSurrogate = errors.New("unpaired surrogate escape")
+
+// ErrTooDeep signals that arrays and objects are nested deeper than allowed.
+var ErrTooDeep = errors.New("nesting too deep")
+
+// ErrInputTooLarge signals that the input data exceeds the allowed size.
+var ErrInputTooLarge = errors.New("input too large")
+
+// ErrTooManyElements signals that the syntax tree exceeds the allowed size.
+var ErrTooManyElements = errors.New("too many elements")
+
+// ErrStringTooLong signals that a string exceeds the allowed length.
+var ErrStringTooLong = errors.New("string too long")
+
+// ErrNumberTooLong signals that a number has more digits than allowed.
+var ErrNumberTooLong = errors.New("number too long")
+
+// ErrTooManyMembers signals that an object has more members than allowed.
+var ErrTooManyMembers = errors.New("too many object members")
+
+// ParseError is an error together with the offset in the input data where
+// it was detected.
+type ParseError struct {
+	Offset int   // offset of the offending element within the input data
+	Err    error // underlying error, e.g. ErrTooDeep
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v at offset %d", e.Err, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Syntax selects the dialect of JSON that the parser accepts.
+type Syntax int
+
+const (
+	// SyntaxJSON is JSON according to RFC 8259. This is the default.
+	SyntaxJSON Syntax = iota
+	// SyntaxJSON5 is JSON5 according to https://spec.json5.org, which adds
+	// comments, trailing commas, single-quoted strings, identifiers as
+	// member names, further number formats and escape sequences.
+	SyntaxJSON5
+	// SyntaxJSONC is JSON with comments and trailing commas, like the
+	// settings.json of VS Code. Unlike with JSON5, comments are kept as
+	// elements of the syntax tree.
+	SyntaxJSONC
+)
+
+// trailingCommas tells whether the syntax allows a comma after the last
+// element of arrays and objects.
+func (s Syntax) trailingCommas() bool {
+	return s == SyntaxJSON5 || s == SyntaxJSONC
+}
+
+// DefaultMaxDepth is the maximum nesting depth unless configured otherwise.
+const DefaultMaxDepth = 10000
+
+// ParseOptions configures the parser. The zero value gives the defaults.
+type ParseOptions struct {
+	// Syntax is the accepted dialect of JSON.
+	Syntax Syntax
+
+	// MaxDepth is the maximum nesting depth of arrays and objects. Zero means
+	// DefaultMaxDepth, a negative value means no limit.
+	MaxDepth int
+
+	// The following limits are disabled when zero or negative.
+
+	// MaxInputSize is the maximum size of the input data in bytes.
+	MaxInputSize int
+	// MaxElements is the maximum number of elements of the syntax tree,
+	// including the root element.
+	MaxElements int
+	// MaxStringLength is the maximum length of a string token in bytes,
+	// without the quotes but including escape sequences.
+	MaxStringLength int
+	// MaxNumberLength is the maximum number of digits of a number, including
+	// those of the fraction and the exponent.
+	MaxNumberLength int
+	// MaxMembers is the maximum number of members of a single object.
+	MaxMembers int
+
+	// ReplaceInvalidUTF8 accepts strings that aren't valid UTF-8. Decoding
+	// them replaces the invalid bytes with U+FFFD.
+	ReplaceInvalidUTF8 bool
+	// AllowLoneSurrogates accepts escape sequences like "\uD800" for UTF-16
+	// surrogates that are not part of a pair. Decoding them gives U+FFFD.
+	AllowLoneSurrogates bool
+
+	// RejectBOM fails with ErrByteOrderMark for input starting with a byte
+	// order mark. By default, it is skipped.
+	RejectBOM bool
+
+	// DuplicateKeys determines how objects with duplicate member names are
+	// treated. By default, all members are kept.
+	DuplicateKeys DuplicateKeyPolicy
+
+	// Parallelism is the number of goroutines that tokenize large inputs
+	// concurrently, e.g. runtime.NumCPU(). Zero or one tokenize on a single
+	// goroutine. It only applies to SyntaxJSON and inputs of at least a few
+	// megabytes.
+	Parallelism int
+}
+
+// maxDepth returns the effective maximum nesting depth, -1 for no limit.
+func (o ParseOptions) maxDepth() int {
+	switch {
+	case o.MaxDepth == 0:
+		return DefaultMaxDepth
+	case o.MaxDepth < 0:
+		return -1
+	default:
+		return o.MaxDepth
+	}
+}
+
 // JSONElement is an element of the JSON syntax tree.
 type JSONElement struct {
 	tpe    int // type according to the t* constants above
@@ -37,7 +172,7 @@ type JSONElement struct {
 	parent int // index of the parent element in the output data
 }
 
-func findMatchingQuotes(data []byte, cur, length int) (int, error) {
+func findMatchingQuotes(data []byte, cur, length int, syntax Syntax) (int, error) {
 	const (
 		openingQuotes = iota
 		character
@@ -46,21 +181,27 @@ func findMatchingQuotes(data []byte, cur, length int) (int, error) {
 
 	res := 0
 	state := openingQuotes
+	quote := byte('"')
 	for {
 		// get next glyph
 		if cur+res == length {
 			// no more data
-			return 0, ErrInvalidToken
+			return 0, ErrUnexpectedEOF
 		}
 		c := data[cur+res]
 
 		switch state {
 		case openingQuotes:
-			switch c {
-			case '"':
+			switch {
+			case c == '"':
 				// consume quotes
 				res++
 				state = character
+			case c == '\'' && syntax == SyntaxJSON5:
+				// consume single quote, which must also close the string
+				quote = c
+				res++
+				state = character
 			default:
 				return 0, ErrInvalidToken
 			}
@@ -70,12 +211,15 @@ func findMatchingQuotes(data []byte, cur, length int) (int, error) {
 				// consume backslash
 				res++
 				state = backslashEscaped
-			case c == '"':
+			case c == quote:
 				// consume closing quote and finish
 				res++
 				return res, nil
-			case c < 32:
-				// control byte
+			case c == '\n' || c == '\r':
+				// line terminators must be escaped
+				return res, ErrInvalidToken
+			case c < 32 && syntax != SyntaxJSON5:
+				// control byte, only JSON5 allows them unescaped
 				return res, ErrInvalidToken
 			default:
 				// consume character
@@ -92,30 +236,91 @@ func findMatchingQuotes(data []byte, cur, length int) (int, error) {
 				// consume Unicode start marker
 				res++
 				// check next
-				for i := 0; i != 4; i++ {
-					// get next glyph
-					if cur+res == length {
-						// no more data
-						return 0, ErrInvalidToken
-					}
-					switch data[cur+res] {
-					case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f', 'A', 'B', 'C', 'D', 'E', 'F':
-						// consume hex digit
-						res++
-					default:
-						// invalid Unicode
-						return 0, ErrInvalidToken
+				if !isHex(data[cur+res:length], 4) {
+					if rest := data[cur+res : length]; len(rest) < 4 && isHex(rest, len(rest)) {
+						// truncated Unicode
+						return 0, ErrUnexpectedEOF
 					}
+					// invalid Unicode
+					return 0, ErrInvalidToken
 				}
+				res += 4
 				state = character
 			default:
-				return 0, ErrInvalidToken
+				if syntax != SyntaxJSON5 {
+					return 0, ErrInvalidToken
+				}
+				size, err := json5Escape(data[cur+res : length])
+				if err != nil {
+					return 0, err
+				}
+				res += size
+				state = character
+			}
+		}
+	}
+}
+
+// isHex tells whether the data starts with the given number of hex digits.
+func isHex(data []byte, count int) bool {
+	if len(data) < count {
+		return false
+	}
+	for _, c := range data[:count] {
+		switch c {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f', 'A', 'B', 'C', 'D', 'E', 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// checkString validates the content of a string token, which must have been
+// found by findMatchingQuotes. It returns the offset of the offending byte
+// within the token and an error if the token contains invalid UTF-8 or an
+// unpaired surrogate escape, unless the options allow that.
+func checkString(token []byte, opts ParseOptions) (int, error) {
+	for i := 1; i < len(token)-1; {
+		c := token[i]
+		switch {
+		case c == '\\' && token[i+1] == 'u':
+			r, _ := decodeHex4(token[i+2:])
+			if !utf16.IsSurrogate(r) {
+				i += 6
+				break
+			}
+			// a high surrogate must be followed by a low surrogate
+			if r < 0xdc00 && i+12 <= len(token)-1 && token[i+6] == '\\' && token[i+7] == 'u' {
+				if r2, _ := decodeHex4(token[i+8:]); r2 >= 0xdc00 && r2 <= 0xdfff {
+					i += 12
+					break
+				}
+			}
+			if !opts.AllowLoneSurrogates {
+				return i, ErrLoneSurrogate
 			}
+			i += 6
+		case c == '\\' && token[i+1] < utf8.RuneSelf:
+			i += 2
+		case c == '\\':
+			// JSON5 escape of a non-ASCII character, which is validated
+			// like the unescaped one
+			i++
+		case c < utf8.RuneSelf:
+			i++
+		default:
+			r, size := utf8.DecodeRune(token[i:])
+			if r == utf8.RuneError && size == 1 && !opts.ReplaceInvalidUTF8 {
+				return i, ErrInvalidUTF8
+			}
+			i += size
 		}
 	}
+	return 0, nil
 }
 
-func findEndOfNumber(data []byte, cur, length int) (int, error) {
+func findEndOfNumber(data []byte, cur, length int, syntax Syntax) (int, error) {
 	const (
 		optionalSign = iota
 		nonfractionStart
@@ -123,13 +328,18 @@ func findEndOfNumber(data []byte, cur, length int) (int, error) {
 		nonfractionContinued
 		radixSeparator
 		fractionStart
+		fractionOptional
 		fractionContinued
 		exponentSeparator
 		exponentSign
 		exponentStart
 		exponentContinued
+		hexStart
+		hexContinued
+		named
 	)
 
+	json5 := syntax == SyntaxJSON5
 	res := 0
 	state := optionalSign
 loop:
@@ -143,35 +353,63 @@ loop:
 		switch state {
 		case optionalSign:
 			// if it's a minus sign, skip it
-			if c == '-' {
+			if c == '-' || (c == '+' && json5) {
 				res++
 			}
 			state = nonfractionStart
 
 		case nonfractionStart:
-			switch c {
-			case '0':
+			switch {
+			case c == '0':
 				// consume non-fractional digit
 				res++
 				state = leadingZero
-			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			case c >= '1' && c <= '9':
 				// consume non-fractional digit
 				res++
 				state = nonfractionContinued
+			case c == '.' && json5:
+				// consume leading radix separator, digits must follow
+				res++
+				state = fractionStart
+			case json5 && (isTruncated(data[cur+res:length], "Infinity") || isTruncated(data[cur+res:length], "NaN")):
+				// named number truncated by the end of the data
+				return 0, ErrUnexpectedEOF
+			case c == 'I' && json5 && bytes.HasPrefix(data[cur+res:length], []byte("Infinity")):
+				res += len("Infinity")
+				state = named
+				break loop
+			case c == 'N' && json5 && bytes.HasPrefix(data[cur+res:length], []byte("NaN")):
+				res += len("NaN")
+				state = named
+				break loop
 			default:
 				break loop
 			}
 
 		case leadingZero:
-			// expect fractional digits next
-			// This will also cause an error if parsing stops here.
-			state = fractionStart
-			if c == '.' {
+			switch c {
+			case '.':
 				// consume radix separator
 				res++
-			} else {
-				// stop parsing
-				break loop
+				state = fractionStart
+				if json5 {
+					state = fractionOptional
+				}
+			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				// leading zeros are not allowed
+				return 0, ErrInvalidToken
+			case 'x', 'X':
+				if !json5 {
+					state = exponentSeparator
+					break
+				}
+				// consume hexadecimal prefix
+				res++
+				state = hexStart
+			default:
+				// no fraction, but there may be an exponent
+				state = exponentSeparator
 			}
 
 		case nonfractionContinued:
@@ -192,18 +430,25 @@ loop:
 				// consume radix separator
 				res++
 				state = fractionStart
+				if json5 {
+					// JSON5 allows a trailing radix separator
+					state = fractionOptional
+				}
 			default:
 				state = exponentSeparator
 			}
 
-		case fractionStart:
+		case fractionStart, fractionOptional:
 			switch c {
 			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 				// consume fractional digits
 				res++
-				state = nonfractionContinued
+				state = fractionContinued
 			default:
-				break loop
+				if state == fractionStart {
+					break loop
+				}
+				state = exponentSeparator
 			}
 
 		case fractionContinued:
@@ -256,296 +501,388 @@ loop:
 			default:
 				break loop
 			}
+
+		case hexStart, hexContinued:
+			if !isHex(data[cur+res:length], 1) {
+				break loop
+			}
+			// consume hexadecimal digit
+			res++
+			state = hexContinued
 		}
 	}
 
 	// check final state, there must not be incomplete parts
 	switch state {
-	case optionalSign, nonfractionStart, fractionStart, exponentSign, exponentStart:
+	case optionalSign, nonfractionStart, fractionStart, exponentSign, exponentStart, hexStart:
+		if cur+res == length {
+			// number token truncated by the end of the data
+			return 0, ErrUnexpectedEOF
+		}
 		// incomplete number token
 		return 0, ErrInvalidToken
-	case leadingZero, nonfractionContinued, radixSeparator, fractionContinued, exponentSeparator, exponentContinued:
+	case leadingZero, nonfractionContinued, radixSeparator, fractionOptional, fractionContinued, exponentSeparator, exponentContinued, hexContinued, named:
 		return res, nil
 	default:
 		return 0, errors.New("invalid state parsing number")
 	}
 }
 
-func parseJSON(data []byte) ([]JSONElement, error) {
-	// create a channel to receive errors from
-	exc := make(chan error)
-
-	// start parsing in a goroutine which emits the resulting tokens to this channel
-	tokens := make(chan JSONElement)
-	go func() {
-		// close error channel on exit to terminate waiting loop
-		defer close(exc)
-
-		length := len(data)
-		cur := 0
-		for cur != length {
-			switch data[cur] {
-			case ' ', '\n', '\r', '\t':
-				fmt.Println(cur, "whitespace")
-				// skip whitespace
-				cur++
-			case '{':
-				fmt.Println(cur, "opening braces")
-				tokens <- JSONElement{tpe: tObjectStart, offset: cur}
-				cur++
-			case '}':
-				fmt.Println(cur, "closing braces")
-				tokens <- JSONElement{tpe: tObjectEnd, offset: cur}
-				cur++
-			case '[':
-				fmt.Println(cur, "opening brackets")
-				tokens <- JSONElement{tpe: tArrayStart, offset: cur}
-				cur++
-			case ']':
-				fmt.Println(cur, "closing brackets")
-				tokens <- JSONElement{tpe: tArrayEnd, offset: cur}
-				cur++
-			case ':':
-				fmt.Println(cur, "colon")
-				tokens <- JSONElement{tpe: tColon, offset: cur}
-				cur++
-			case ',':
-				fmt.Println(cur, "comma")
-				tokens <- JSONElement{tpe: tComma, offset: cur}
-				cur++
-			case '"':
-				fmt.Println(cur, "string")
-				size, err := findMatchingQuotes(data, cur, length)
-				if err != nil {
-					exc <- err
-					return
-				}
-				tokens <- JSONElement{tpe: tString, offset: cur}
-				cur += size
-			case 'n':
-				fmt.Println(cur, "null")
-				if cur+4 > length {
-					exc <- ErrInvalidToken
-					return
-				}
-				if (data[cur+1] != 'u') || (data[cur+2] != 'l') || (data[cur+3] != 'l') {
-					exc <- ErrInvalidToken
-				}
-				tokens <- JSONElement{tpe: tNull, offset: cur}
-				cur += 4
-			case 't':
-				fmt.Println(cur, "true")
-				if cur+4 > length {
-					exc <- ErrInvalidToken
-					return
-				}
-				if (data[cur+1] != 'r') || (data[cur+2] != 'u') || (data[cur+3] != 'e') {
-					exc <- ErrInvalidToken
-				}
-				tokens <- JSONElement{tpe: tBool, offset: cur}
-				cur += 4
-			case 'f':
-				fmt.Println(cur, "false")
-				if cur+5 > length {
-					exc <- ErrInvalidToken
-					return
-				}
-				if (data[cur+1] != 'a') || (data[cur+2] != 'l') || (data[cur+3] != 's') || (data[cur+4] != 'e') {
-					exc <- ErrInvalidToken
-				}
-				tokens <- JSONElement{tpe: tBool, offset: cur}
-				cur += 5
-			case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-				fmt.Println(cur, "number")
-				size, err := findEndOfNumber(data, cur, length)
-				if err != nil {
-					exc <- err
-					return
-				}
-				tokens <- JSONElement{tpe: tNumber, offset: cur}
-				cur += size
-			default:
-				fmt.Println(cur, "unexpected")
-				exc <- ErrInvalidToken
-				return
-			}
+// tokenize splits the data into tokens and passes them to emit, which returns
+// false to stop tokenizing. Note that the parent of the tokens isn't set.
+func tokenize(data []byte, opts ParseOptions, emit func(JSONElement) bool) error {
+	length := len(data)
+	cur := 0
+	for cur != length {
+		tpe, size, err := nextToken(data, cur, opts)
+		if err != nil {
+			return err
+		}
+		if tpe != tNone && !emit(JSONElement{tpe: tpe, offset: cur}) {
+			return nil
 		}
-	}()
+		cur += size
+	}
+	return nil
+}
 
-	res := make([]JSONElement, 0, 10)
-	res = append(res, JSONElement{tpe: tRoot})
-	context := 0
-	for {
-		select {
-		case err := <-exc:
-			// Note that "err" can be nil, which happens when the channel
-			// is closed and it just means that the goroutine finished.
-			fmt.Println("received error", err)
-			return res, err
-		case elem := <-tokens:
-			fmt.Println("received element", elem)
-			// determine context changes
-			switch elem.tpe {
-			case tArrayStart, tObjectStart:
-				// remember parent index for aggregate value
-				elem.parent = context
-				context = len(res)
-			case tArrayEnd:
-				if res[context].tpe != tArrayStart {
-					// current context must be an array
-					return nil, ErrInvalidStructure
-				}
-				// validate all intermediate tokens
-				const (
-					start = iota // initial state, next token must be a value if present
-					comma        // next token must be a comma if present
-					next         // next token must be present and not a comma
-				)
-				state := start
-				for i := context + 1; i != len(res); i++ {
-					t := res[i]
-					// if this is not a direct child, ignore it
-					if t.parent != context {
-						continue
-					}
-					// if this is the end of a nested structure, ignore it
-					if t.tpe == tObjectEnd || t.tpe == tArrayEnd {
-						continue
-					}
+// nextToken returns the type and size of the token at the given offset. The
+// type is tNone for whitespace.
+func nextToken(data []byte, cur int, opts ParseOptions) (int, int, error) {
+	length := len(data)
+	if opts.Syntax == SyntaxJSON5 {
+		// tokens that only exist in JSON5, everything else is handled like
+		// in JSON below
+		tpe, size, err := nextJSON5Token(data, cur, opts)
+		if err != nil || size > 0 {
+			return tpe, size, err
+		}
+	}
 
-					switch t.tpe {
-					case tObjectStart, tArrayStart, tBool, tNumber, tNull, tString:
-						if state == comma {
-							// expected a comma as separator, not a value
-							return nil, ErrInvalidStructure
-						}
-						state = comma
-					case tComma:
-						if state != comma {
-							// expected a value, not a comma as separator
-							return nil, ErrInvalidStructure
-						}
-						state = next
-					default:
-						// unexpected token as array element
-						return nil, ErrInvalidStructure
-					}
-				}
-				if state == next {
-					// brackets are not empty but don't end in a value
-					return nil, ErrInvalidStructure
-				}
-				context = res[context].parent
-				elem.parent = context
-			case tObjectEnd:
-				if res[context].tpe != tObjectStart {
-					// current context must be an object
-					return nil, ErrInvalidStructure
-				}
-				// validate all intermediate tokens
-				const (
-					start = iota // initial state, next token must be a string if present
-					colon        // next token must be present and a colon
-					value        // next token must be present and a value
-					comma        // next token must be a comma if present
-					next         // next token must be present and be a string
-				)
-				state := start
-				for i := context + 1; i != len(res); i++ {
-					t := res[i]
-					// if this is not a direct child, ignore it
-					if t.parent != context {
-						continue
-					}
-					// if this is the end of a nested structure, ignore it
-					if t.tpe == tObjectEnd || t.tpe == tArrayEnd {
-						continue
-					}
+	switch data[cur] {
+	case ' ', '\n', '\r', '\t':
+		return tNone, 1, nil
+	case '{':
+		return tObjectStart, 1, nil
+	case '}':
+		return tObjectEnd, 1, nil
+	case '[':
+		return tArrayStart, 1, nil
+	case ']':
+		return tArrayEnd, 1, nil
+	case ':':
+		return tColon, 1, nil
+	case ',':
+		return tComma, 1, nil
+	case '/':
+		if opts.Syntax != SyntaxJSONC {
+			return tNone, 0, ErrInvalidToken
+		}
+		size, err := findEndOfComment(data, cur, length)
+		return tComment, size, err
+	case '"':
+		size, err := stringToken(data, cur, opts)
+		return tString, size, err
+	case 'n':
+		if cur+4 > length {
+			return tNone, 0, truncatedLiteral(data[cur:], "null")
+		}
+		if (data[cur+1] != 'u') || (data[cur+2] != 'l') || (data[cur+3] != 'l') {
+			return tNone, 0, ErrInvalidToken
+		}
+		return tNull, 4, nil
+	case 't':
+		if cur+4 > length {
+			return tNone, 0, truncatedLiteral(data[cur:], "true")
+		}
+		if (data[cur+1] != 'r') || (data[cur+2] != 'u') || (data[cur+3] != 'e') {
+			return tNone, 0, ErrInvalidToken
+		}
+		return tBool, 4, nil
+	case 'f':
+		if cur+5 > length {
+			return tNone, 0, truncatedLiteral(data[cur:], "false")
+		}
+		if (data[cur+1] != 'a') || (data[cur+2] != 'l') || (data[cur+3] != 's') || (data[cur+4] != 'e') {
+			return tNone, 0, ErrInvalidToken
+		}
+		return tBool, 5, nil
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		size, err := numberToken(data, cur, opts)
+		return tNumber, size, err
+	default:
+		return tNone, 0, ErrInvalidToken
+	}
+}
 
-					switch state {
-					case start, next:
-						if t.tpe != tString {
-							// expected a string as key
-							return nil, ErrInvalidStructure
-						}
-						state = colon
-					case colon:
-						if t.tpe != tColon {
-							// expected a colon as separator
-							return nil, ErrInvalidStructure
-						}
-						state = value
-					case value:
-						switch t.tpe {
-						case tObjectStart, tArrayStart, tBool, tNumber, tNull, tString:
-							state = comma
-						default:
-							// expected a value
-							return nil, ErrInvalidStructure
-						}
-					case comma:
-						if t.tpe != tComma {
-							// expected a comma as separator
-							return nil, ErrInvalidStructure
-						}
-						state = next
-					}
-				}
-				switch state {
-				case colon, value, next:
-					// braces are not empty but don't end in a value
-					return nil, ErrInvalidStructure
-				}
-				context = res[context].parent
-				elem.parent = context
-			case tComma:
-				if res[context].tpe != tArrayStart && res[context].tpe != tObjectStart {
-					return nil, ErrInvalidStructure
-				}
-				elem.parent = context
-			case tColon:
-				if res[context].tpe != tObjectStart {
-					return nil, ErrInvalidStructure
-				}
-				elem.parent = context
-			default:
-				elem.parent = context
+// truncatedLiteral returns the error for a literal like "null" that isn't
+// complete at the end of the data.
+func truncatedLiteral(data []byte, literal string) error {
+	if isTruncated(data, literal) {
+		return ErrUnexpectedEOF
+	}
+	return ErrInvalidToken
+}
+
+// isTruncated tells whether the data is a proper prefix of the literal.
+func isTruncated(data []byte, literal string) bool {
+	return len(data) < len(literal) && strings.HasPrefix(literal, string(data))
+}
+
+// stringToken returns the size of the string token at the given offset,
+// checking it against the options.
+func stringToken(data []byte, cur int, opts ParseOptions) (int, error) {
+	size, err := findMatchingQuotes(data, cur, len(data), opts.Syntax)
+	if err != nil {
+		return 0, err
+	}
+	if opts.MaxStringLength > 0 && size-2 > opts.MaxStringLength {
+		return 0, &ParseError{Offset: cur, Err: ErrStringTooLong}
+	}
+	if offset, err := checkString(data[cur:cur+size], opts); err != nil {
+		return 0, &ParseError{Offset: cur + offset, Err: err}
+	}
+	return size, nil
+}
+
+// numberToken returns the size of the number token at the given offset,
+// checking it against the options.
+func numberToken(data []byte, cur int, opts ParseOptions) (int, error) {
+	size, err := findEndOfNumber(data, cur, len(data), opts.Syntax)
+	if err != nil {
+		return 0, err
+	}
+	if opts.MaxNumberLength > 0 && countDigits(data[cur:cur+size]) > opts.MaxNumberLength {
+		return 0, &ParseError{Offset: cur, Err: ErrNumberTooLong}
+	}
+	return size, nil
+}
+
+// countDigits returns the number of digits in the token, which are
+// hexadecimal digits for JSON5 hexadecimal numbers.
+func countDigits(token []byte) int {
+	if i := bytes.IndexAny(token, "xX"); i >= 0 {
+		return len(token) - i - 1
+	}
+	res := 0
+	for _, c := range token {
+		if c >= '0' && c <= '9' {
+			res++
+		}
+	}
+	return res
+}
+
+func parseJSON(data []byte) ([]JSONElement, error) {
+	return parseJSONOptions(data, ParseOptions{})
+}
+
+func parseJSONOptions(data []byte, opts ParseOptions) ([]JSONElement, error) {
+	if opts.MaxInputSize > 0 && len(data) > opts.MaxInputSize {
+		return nil, &ParseError{Offset: opts.MaxInputSize, Err: ErrInputTooLarge}
+	}
+	chunks := len(data) / minParallelChunk
+	if opts.Parallelism < chunks {
+		chunks = opts.Parallelism
+	}
+	if chunks > 1 {
+		return parseJSONParallel(data, opts, chunks)
+	}
+
+	// build the syntax tree from the tokens, validating the structure
+	p := newIncrementalParser(data, opts, true)
+	err := tokenize(data, opts, func(elem JSONElement) bool {
+		p.token(elem)
+		return !p.stopped
+	})
+	if err != nil {
+		return p.res, err
+	}
+	return p.result()
+}
+
+// readInput reads the whole file of the given name, or the standard input
+// for "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// displayName returns the name of the input for messages.
+func displayName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
+// readSamples reads the sample documents from the given files. With ndjson,
+// every line of the files is a separate document.
+func readSamples(names []string, ndjson bool) ([]*Document, error) {
+	if len(names) == 0 {
+		return nil, errors.New("expected at least one file")
+	}
+
+	res := []*Document{}
+	for _, name := range names {
+		data, err := readInput(name)
+		if err != nil {
+			return nil, err
+		}
+		name = displayName(name)
+		if ndjson {
+			docs, err := ParseNDJSON(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			res = append(res, docs...)
+		} else {
+			doc, err := ParseDocument(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
 			}
-			res = append(res, elem)
-			break
+			res = append(res, doc)
 		}
 	}
+	return res, nil
 }
 
-func main() {
-	defer fmt.Println("Done.")
-	if len(os.Args) != 2 {
-		fmt.Println("expected exactly one arguent")
-		return
+// runInfer implements the "infer" subcommand, which emits a JSON Schema
+// describing the given sample files.
+func runInfer(args []string) error {
+	flags := flag.NewFlagSet("infer", flag.ContinueOnError)
+	ndjson := flags.Bool("ndjson", false, "treat every line of the files as separate document")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	docs, err := readSamples(flags.Args(), *ndjson)
+	if err != nil {
+		return err
 	}
 
-	file, err := os.Open(os.Args[1])
+	_, err = os.Stdout.Write(InferSchema(docs...))
+	return err
+}
+
+// runGenGo implements the "gen-go" subcommand, which emits Go type
+// declarations for the given sample files.
+func runGenGo(args []string) error {
+	flags := flag.NewFlagSet("gen-go", flag.ContinueOnError)
+	ndjson := flags.Bool("ndjson", false, "treat every line of the files as separate document")
+	packageName := flags.String("package", "main", "name of the generated package")
+	typeName := flags.String("type", "Root", "name of the generated root type")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	docs, err := readSamples(flags.Args(), *ndjson)
 	if err != nil {
-		fmt.Println("failed to open file", err)
-		return
+		return err
 	}
 
-	data := make([]byte, 0, 10000)
-	size, err := file.Read(data)
+	src, err := GenerateGo(*packageName, *typeName, docs...)
 	if err != nil {
-		fmt.Println("failed to read file", err)
-		return
+		return err
+	}
+	_, err = os.Stdout.Write(src)
+	return err
+}
+
+// runRepair implements the "repair" subcommand, which writes the repaired
+// document of the given file and reports the applied fixes on stderr.
+func runRepair(args []string) error {
+	flags := flag.NewFlagSet("repair", flag.ContinueOnError)
+	quiet := flags.Bool("q", false, "don't report the applied fixes")
+	if err := flags.Parse(args); err != nil {
+		return err
 	}
-	if size == cap(data) {
-		fmt.Println("file too large")
-		return
+	if flags.NArg() != 1 {
+		return errors.New("expected exactly one file")
 	}
 
-	res, err := parseJSON(data)
+	data, err := readInput(flags.Arg(0))
 	if err != nil {
-		fmt.Println("failed to parse", err)
-		return
+		return err
+	}
+	repaired, fixes, err := Repair(data)
+	if !*quiet {
+		for _, fix := range fixes {
+			fmt.Fprintln(os.Stderr, displayName(flags.Arg(0))+":", fix)
+		}
 	}
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(repaired)
+	return err
+}
 
-	fmt.Println("parsed", res)
+// runParse implements the default command, which parses the given files and
+// prints the syntax tree or the diagnostics for each of them. It fails if any
+// of the files is invalid.
+func runParse(names []string) error {
+	if len(names) == 0 {
+		return errors.New("expected at least one file")
+	}
+
+	color := isTerminal(os.Stdout)
+	invalid := 0
+	for _, name := range names {
+		data, err := readInput(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			invalid++
+			continue
+		}
+		name = displayName(name)
+		doc, err := ParseDocument(data)
+		if err != nil {
+			diagnostics := Diagnose(data, ParseOptions{})
+			if len(diagnostics) == 0 {
+				fmt.Fprintln(os.Stderr, name+":", err)
+			}
+			for _, d := range diagnostics {
+				if err := writeDiagnostic(os.Stdout, name, d, color); err != nil {
+					return err
+				}
+			}
+			invalid++
+			continue
+		}
+		fmt.Println(name+":", "parsed", doc.elements)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d files invalid", invalid, len(names))
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "infer":
+			run = runInfer
+		case "gen-go":
+			run = runGenGo
+		case "repair":
+			run = runRepair
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, os.Args[1]+":", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	if err := runParse(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"errors"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 type jsonTest struct {
@@ -10,322 +14,370 @@ type jsonTest struct {
 	err      error
 }
 
-func TestParseJSON(t *testing.T) {
-	cases := map[string]jsonTest{
-		"empty": {
-			data: []byte(``),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-			},
-		},
-		"string 1": {
-			data: []byte(`""`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 2": {
-			data: []byte(`"string"`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 3": {
-			data: []byte(`"\""`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 4": {
-			data: []byte(`"\\"`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 5": {
-			data: []byte(`"\/"`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 6": {
-			data: []byte(`"\b"`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 7": {
-			data: []byte(`"\n"`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 8": {
-			data: []byte(`"\t"`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"string 9": {
-			data: []byte(`"\u1234"`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 0, parent: 0},
-			},
-		},
-		"null": {
-			data: []byte(`null`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tNull, offset: 0, parent: 0},
-			},
-		},
-		"bool true": {
-			data: []byte(`true`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tBool, offset: 0, parent: 0},
-			},
-		},
-		"bool false": {
-			data: []byte(`false`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tBool, offset: 0, parent: 0},
-			},
-		},
-		"number 1": {
-			data: []byte(`0`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tNumber, offset: 0, parent: 0},
-			},
-		},
-		"number 2": {
-			data: []byte(`-1234`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tNumber, offset: 0, parent: 0},
-			},
-		},
-		"number 3": {
-			data: []byte(`1.2`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tNumber, offset: 0, parent: 0},
-			},
-		},
-		"number 4": {
-			data: []byte(`1E1`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tNumber, offset: 0, parent: 0},
-			},
-		},
-		"number 5": {
-			data: []byte(`1e-1`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tNumber, offset: 0, parent: 0},
-			},
-		},
-		"number 6": {
-			data: []byte(`0.314e+1`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tNumber, offset: 0, parent: 0},
-			},
-		},
-		"array 1": {
-			data: []byte(`[]`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
-				JSONElement{tpe: tArrayEnd, offset: 1, parent: 0},
-			},
-		},
-		"array 2": {
-			data: []byte(`["", true]`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 1, parent: 1},
-				JSONElement{tpe: tComma, offset: 3, parent: 1},
-				JSONElement{tpe: tBool, offset: 5, parent: 1},
-				JSONElement{tpe: tArrayEnd, offset: 9, parent: 0},
-			},
-		},
-		"array 3": {
-			data: []byte(`[[""]]`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
-				JSONElement{tpe: tArrayStart, offset: 1, parent: 1},
-				JSONElement{tpe: tString, offset: 2, parent: 2},
-				JSONElement{tpe: tArrayEnd, offset: 4, parent: 1},
-				JSONElement{tpe: tArrayEnd, offset: 5, parent: 0},
-			},
-		},
-		"array 5": {
-			data: []byte(`[1, 2]`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
-				JSONElement{tpe: tNumber, offset: 1, parent: 1},
-				JSONElement{tpe: tComma, offset: 2, parent: 1},
-				JSONElement{tpe: tNumber, offset: 4, parent: 1},
-				JSONElement{tpe: tArrayEnd, offset: 5, parent: 0},
-			},
-		},
-		"object 1": {
-			data: []byte(`{}`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tObjectStart, offset: 0, parent: 0},
-				JSONElement{tpe: tObjectEnd, offset: 1, parent: 0},
-			},
-		},
-		"object 2": {
-			data: []byte(`{"k": true}`),
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tObjectStart, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 1, parent: 1},
-				JSONElement{tpe: tColon, offset: 4, parent: 1},
-				JSONElement{tpe: tBool, offset: 6, parent: 1},
-				JSONElement{tpe: tObjectEnd, offset: 10, parent: 0},
-			},
-		},
-		"whitespace 1": {
-			data: []byte{'\n', '"', '"'},
-			elements: []JSONElement{
-				JSONElement{tpe: tRoot, offset: 0, parent: 0},
-				JSONElement{tpe: tString, offset: 1, parent: 0},
-			},
-		},
-		"invalid 1": {
-			data: []byte(`a`),
-			err:  ErrInvalidToken,
-		},
-		"invalid 3": {
-			data: []byte{0},
-			err:  ErrInvalidToken,
-		},
-		"invalid 4": {
-			data: []byte(`nil`),
-			err:  ErrInvalidToken,
-		},
-		"invalid 7": {
-			data: []byte(".2"),
-			err:  ErrInvalidToken,
-		},
-		"invalid 8": {
-			data: []byte("1."),
-			err:  ErrInvalidToken,
-		},
-		"invalid 9": {
-			data: []byte("1.2E"),
-			err:  ErrInvalidToken,
-		},
-		"invalid 10": {
-			data: []byte("1.2E+"),
-			err:  ErrInvalidToken,
-		},
-		"invalid 11": {
-			data: []byte("1.2e-"),
-			err:  ErrInvalidToken,
-		},
-		"invalid 12": {
-			data: []byte("01.2"),
-			err:  ErrInvalidToken,
-		},
-		"invalid 13": {
-			data: []byte("01"),
-			err:  ErrInvalidToken,
-		},
-		"invalid string 1": {
-			data: []byte(`"`),
-			err:  ErrInvalidToken,
-		},
-		"invalid string 2": {
-			data: []byte{'"', 0, '"'},
-			err:  ErrInvalidToken,
-		},
-		"invalid string 3": {
-			data: []byte("\"\n\""),
-			err:  ErrInvalidToken,
-		},
-		"invalid string 4": {
-			data: []byte(`"\M"`),
-			err:  ErrInvalidToken,
-		},
-		"invalid string 5": {
-			data: []byte(`"\ux1234"`),
-			err:  ErrInvalidToken,
-		},
-		"invalid structure 1": {
-			data: []byte("}"),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 2": {
-			data: []byte("]"),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 3": {
-			data: []byte("[,]"),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 4": {
-			data: []byte("[1,]"),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 5": {
-			data: []byte("[1:2]"),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 6": {
-			data: []byte("[true false]"),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 7": {
-			data: []byte(`{"k":}`),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 8": {
-			data: []byte(`{"k":"v",}`),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 9": {
-			data: []byte(`{1: 2}`),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 10": {
-			data: []byte(`{"1": 2 : 3}`),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 11": {
-			data: []byte(`{"k" "v"}`),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 12": {
-			data: []byte(`1, 2`),
-			err:  ErrInvalidStructure,
-		},
-		"invalid structure 13": {
-			data: []byte(`"k":"v"`),
-			err:  ErrInvalidStructure,
-		},
-	}
+// parseJSONCases are the test cases for parseJSON, which also seed the fuzz
+// tests.
+var parseJSONCases = map[string]jsonTest{
+	"empty": {
+		data: []byte(``),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+		},
+	},
+	"string 1": {
+		data: []byte(`""`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 2": {
+		data: []byte(`"string"`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 3": {
+		data: []byte(`"\""`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 4": {
+		data: []byte(`"\\"`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 5": {
+		data: []byte(`"\/"`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 6": {
+		data: []byte(`"\b"`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 7": {
+		data: []byte(`"\n"`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 8": {
+		data: []byte(`"\t"`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"string 9": {
+		data: []byte(`"\u1234"`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 0, parent: 0},
+		},
+	},
+	"null": {
+		data: []byte(`null`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNull, offset: 0, parent: 0},
+		},
+	},
+	"bool true": {
+		data: []byte(`true`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tBool, offset: 0, parent: 0},
+		},
+	},
+	"bool false": {
+		data: []byte(`false`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tBool, offset: 0, parent: 0},
+		},
+	},
+	"number 1": {
+		data: []byte(`0`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 0, parent: 0},
+		},
+	},
+	"number 2": {
+		data: []byte(`-1234`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 0, parent: 0},
+		},
+	},
+	"number 3": {
+		data: []byte(`1.2`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 0, parent: 0},
+		},
+	},
+	"number 4": {
+		data: []byte(`1E1`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 0, parent: 0},
+		},
+	},
+	"number 5": {
+		data: []byte(`1e-1`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 0, parent: 0},
+		},
+	},
+	"number 6": {
+		data: []byte(`0.314e+1`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 0, parent: 0},
+		},
+	},
+	"number 7": {
+		data: []byte(`0e5`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 0, parent: 0},
+		},
+	},
+	"array 1": {
+		data: []byte(`[]`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
+			JSONElement{tpe: tArrayEnd, offset: 1, parent: 0},
+		},
+	},
+	"array 2": {
+		data: []byte(`["", true]`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 1, parent: 1},
+			JSONElement{tpe: tComma, offset: 3, parent: 1},
+			JSONElement{tpe: tBool, offset: 5, parent: 1},
+			JSONElement{tpe: tArrayEnd, offset: 9, parent: 0},
+		},
+	},
+	"array 3": {
+		data: []byte(`[[""]]`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
+			JSONElement{tpe: tArrayStart, offset: 1, parent: 1},
+			JSONElement{tpe: tString, offset: 2, parent: 2},
+			JSONElement{tpe: tArrayEnd, offset: 4, parent: 1},
+			JSONElement{tpe: tArrayEnd, offset: 5, parent: 0},
+		},
+	},
+	"array 5": {
+		data: []byte(`[1, 2]`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 1, parent: 1},
+			JSONElement{tpe: tComma, offset: 2, parent: 1},
+			JSONElement{tpe: tNumber, offset: 4, parent: 1},
+			JSONElement{tpe: tArrayEnd, offset: 5, parent: 0},
+		},
+	},
+	"array 6": {
+		data: []byte(`[0,-0.5]`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tArrayStart, offset: 0, parent: 0},
+			JSONElement{tpe: tNumber, offset: 1, parent: 1},
+			JSONElement{tpe: tComma, offset: 2, parent: 1},
+			JSONElement{tpe: tNumber, offset: 3, parent: 1},
+			JSONElement{tpe: tArrayEnd, offset: 7, parent: 0},
+		},
+	},
+	"object 1": {
+		data: []byte(`{}`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tObjectStart, offset: 0, parent: 0},
+			JSONElement{tpe: tObjectEnd, offset: 1, parent: 0},
+		},
+	},
+	"object 2": {
+		data: []byte(`{"k": true}`),
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tObjectStart, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 1, parent: 1},
+			JSONElement{tpe: tColon, offset: 4, parent: 1},
+			JSONElement{tpe: tBool, offset: 6, parent: 1},
+			JSONElement{tpe: tObjectEnd, offset: 10, parent: 0},
+		},
+	},
+	"whitespace 1": {
+		data: []byte{'\n', '"', '"'},
+		elements: []JSONElement{
+			JSONElement{tpe: tRoot, offset: 0, parent: 0},
+			JSONElement{tpe: tString, offset: 1, parent: 0},
+		},
+	},
+	"invalid 1": {
+		data: []byte(`a`),
+		err:  ErrInvalidToken,
+	},
+	"invalid 3": {
+		data: []byte{0},
+		err:  ErrInvalidToken,
+	},
+	"invalid 4": {
+		data: []byte(`nil`),
+		err:  ErrInvalidToken,
+	},
+	"invalid 7": {
+		data: []byte(".2"),
+		err:  ErrInvalidToken,
+	},
+	"invalid 8": {
+		data: []byte("1."),
+		err:  ErrUnexpectedEOF,
+	},
+	"invalid 9": {
+		data: []byte("1.2E"),
+		err:  ErrUnexpectedEOF,
+	},
+	"invalid 10": {
+		data: []byte("1.2E+"),
+		err:  ErrUnexpectedEOF,
+	},
+	"invalid 11": {
+		data: []byte("1.2e-"),
+		err:  ErrUnexpectedEOF,
+	},
+	"invalid 12": {
+		data: []byte("01.2"),
+		err:  ErrInvalidToken,
+	},
+	"invalid 13": {
+		data: []byte("01"),
+		err:  ErrInvalidToken,
+	},
+	"invalid 14": {
+		data: []byte("1.2.3"),
+		err:  ErrInvalidToken,
+	},
+	"invalid 15": {
+		data: []byte("nulx"),
+		err:  ErrInvalidToken,
+	},
+	"invalid 16": {
+		data: []byte("[-01]"),
+		err:  ErrInvalidToken,
+	},
+	"invalid string 1": {
+		data: []byte(`"`),
+		err:  ErrUnexpectedEOF,
+	},
+	"invalid string 2": {
+		data: []byte{'"', 0, '"'},
+		err:  ErrInvalidToken,
+	},
+	"invalid string 3": {
+		data: []byte("\"\n\""),
+		err:  ErrInvalidToken,
+	},
+	"invalid string 4": {
+		data: []byte(`"\M"`),
+		err:  ErrInvalidToken,
+	},
+	"invalid string 5": {
+		data: []byte(`"\ux1234"`),
+		err:  ErrInvalidToken,
+	},
+	"invalid structure 1": {
+		data: []byte("}"),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 2": {
+		data: []byte("]"),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 3": {
+		data: []byte("[,]"),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 4": {
+		data: []byte("[1,]"),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 5": {
+		data: []byte("[1:2]"),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 6": {
+		data: []byte("[true false]"),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 7": {
+		data: []byte(`{"k":}`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 8": {
+		data: []byte(`{"k":"v",}`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 9": {
+		data: []byte(`{1: 2}`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 10": {
+		data: []byte(`{"1": 2 : 3}`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 11": {
+		data: []byte(`{"k" "v"}`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 12": {
+		data: []byte(`1, 2`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 13": {
+		data: []byte(`"k":"v"`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 14": {
+		data: []byte(`1 2`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 15": {
+		data: []byte(`[]{}`),
+		err:  ErrInvalidStructure,
+	},
+	"invalid structure 16": {
+		data: []byte(`[`),
+		err:  ErrUnexpectedEOF,
+	},
+	"invalid structure 17": {
+		data: []byte(`{"k": [1]`),
+		err:  ErrUnexpectedEOF,
+	},
+}
 
-	for name, c := range cases {
+func TestParseJSON(t *testing.T) {
+	for name, c := range parseJSONCases {
 		t.Run(name, func(t *testing.T) {
 			elements, err := parseJSON(c.data)
 			if c.err != nil {
@@ -361,3 +413,155 @@ func TestParseJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestParseJSONGoroutines(t *testing.T) {
+	// parsing must not leave goroutines behind, even when rejecting the
+	// input early
+	before := runtime.NumGoroutine()
+	for i := 0; i != 100; i++ {
+		if _, err := parseJSON([]byte(`] 1 2 3`)); err != ErrInvalidStructure {
+			t.Fatal("unexpected result", err)
+		}
+	}
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines leaked", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseJSONMaxDepth(t *testing.T) {
+	cases := map[string]struct {
+		data     string
+		maxDepth int
+		offset   int // offset of the ErrTooDeep error, -1 if none is expected
+	}{
+		"scalar":            {data: `1`, maxDepth: 1, offset: -1},
+		"at limit":          {data: `[{"a": []}]`, maxDepth: 3, offset: -1},
+		"siblings":          {data: `[[], {}, [], {"a": 1}]`, maxDepth: 2, offset: -1},
+		"beyond limit":      {data: `[{"a": []}]`, maxDepth: 2, offset: 7},
+		"unclosed":          {data: `[[[`, maxDepth: 2, offset: 2},
+		"default":           {data: strings.Repeat(`[`, DefaultMaxDepth) + strings.Repeat(`]`, DefaultMaxDepth), offset: -1},
+		"beyond default":    {data: strings.Repeat(`[`, DefaultMaxDepth+1), offset: DefaultMaxDepth},
+		"unlimited":         {data: strings.Repeat(`[`, DefaultMaxDepth+1) + strings.Repeat(`]`, DefaultMaxDepth+1), maxDepth: -1, offset: -1},
+		"structure first":   {data: `[]] [[`, maxDepth: 1, offset: -1},
+		"nested in between": {data: `[1, [2], 3]`, maxDepth: 2, offset: -1},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseJSONOptions([]byte(c.data), ParseOptions{MaxDepth: c.maxDepth})
+			if c.offset < 0 {
+				if errors.Is(err, ErrTooDeep) {
+					t.Error("unexpected error", err)
+				}
+				return
+			}
+			var e *ParseError
+			if !errors.As(err, &e) || !errors.Is(err, ErrTooDeep) {
+				t.Fatal("expected ErrTooDeep, received", err)
+			}
+			if e.Offset != c.offset {
+				t.Errorf("expected offset %d, received %d", c.offset, e.Offset)
+			}
+		})
+	}
+}
+
+func TestParseJSONLimits(t *testing.T) {
+	cases := map[string]struct {
+		data   string
+		opts   ParseOptions
+		err    error // expected error, nil if none
+		offset int
+	}{
+		"input size":               {data: `[1, 2]`, opts: ParseOptions{MaxInputSize: 6}},
+		"input size exceeded":      {data: `[1, 2] `, opts: ParseOptions{MaxInputSize: 6}, err: ErrInputTooLarge, offset: 6},
+		"elements":                 {data: `[1, 2]`, opts: ParseOptions{MaxElements: 6}},
+		"elements exceeded":        {data: `[1, 2]`, opts: ParseOptions{MaxElements: 5}, err: ErrTooManyElements, offset: 5},
+		"comment exceeds elements": {data: "[1 // c\n]", opts: ParseOptions{Syntax: SyntaxJSONC, MaxElements: 3}, err: ErrTooManyElements, offset: 3},
+		"string length":            {data: `["abc", "\n\t"]`, opts: ParseOptions{MaxStringLength: 4}},
+		"string length exceeded":   {data: `["abc", "\n\t\\"]`, opts: ParseOptions{MaxStringLength: 4}, err: ErrStringTooLong, offset: 8},
+		"number length":            {data: `[-1.5e+10, 1234]`, opts: ParseOptions{MaxNumberLength: 4}},
+		"number length exceeded":   {data: `[-1.5e+10, 12345]`, opts: ParseOptions{MaxNumberLength: 4}, err: ErrNumberTooLong, offset: 11},
+		"members":                  {data: `{"a": 1, "b": {"c": 3, "d": 4}}`, opts: ParseOptions{MaxMembers: 2}},
+		"members exceeded":         {data: `{"a": {"b": 2, "c": 3, "d": 4}}`, opts: ParseOptions{MaxMembers: 2}, err: ErrTooManyMembers, offset: 23},
+		"members in array":         {data: `[{"a": 1}, {"b": 2}, {"c": 3}]`, opts: ParseOptions{MaxMembers: 1}},
+		"limits disabled":          {data: `{"abc": [12345, "x"]}`, opts: ParseOptions{MaxInputSize: -1, MaxElements: -1, MaxStringLength: -1, MaxNumberLength: -1, MaxMembers: -1}},
+		"invalid before exceeded":  {data: `[1, 2,]`, opts: ParseOptions{MaxInputSize: 100}, err: ErrInvalidStructure},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseJSONOptions([]byte(c.data), c.opts)
+			if c.err == nil {
+				if err != nil {
+					t.Error("unexpected failure", err)
+				}
+				return
+			}
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, received %v", c.err, err)
+			}
+			var e *ParseError
+			if errors.As(err, &e) && e.Offset != c.offset {
+				t.Errorf("expected offset %d, received %d", c.offset, e.Offset)
+			}
+		})
+	}
+}
+
+func TestParseJSONStrings(t *testing.T) {
+	lenient := ParseOptions{ReplaceInvalidUTF8: true, AllowLoneSurrogates: true}
+	cases := map[string]struct {
+		data   string
+		opts   ParseOptions
+		value  string // decoded value, if accepted
+		err    error  // expected error, nil if none
+		offset int
+	}{
+		"ascii":                    {data: `"abc"`, value: "abc"},
+		"utf-8":                    {data: "\"\u00e4\u65e5\U0001f600\"", value: "\u00e4\u65e5\U0001f600"},
+		"surrogate pair":           {data: `"\ud83d\ude00"`, value: "\U0001f600"},
+		"invalid byte":             {data: "[\"ab\xffc\"]", err: ErrInvalidUTF8, offset: 4},
+		"truncated sequence":       {data: "\"\xe6\x97\"", err: ErrInvalidUTF8, offset: 1},
+		"overlong":                 {data: "\"a\xc0\xaf\"", err: ErrInvalidUTF8, offset: 2},
+		"encoded surrogate":        {data: "\"\xed\xa0\x80\"", err: ErrInvalidUTF8, offset: 1},
+		"invalid in key":           {data: "{\"\xff\": 1}", err: ErrInvalidUTF8, offset: 2},
+		"replaced":                 {data: "\"a\xffb\xc0\xafc\"", opts: lenient, value: "a\ufffdb\ufffd\ufffdc"},
+		"replaced with escapes":    {data: "\"\\n\xff\"", opts: lenient, value: "\n\ufffd"},
+		"lone high surrogate":      {data: `["x", "a\ud800"]`, err: ErrLoneSurrogate, offset: 8},
+		"lone low surrogate":       {data: `"\udc00"`, err: ErrLoneSurrogate, offset: 1},
+		"inverted surrogates":      {data: `"\udc00\ud800"`, err: ErrLoneSurrogate, offset: 1},
+		"high surrogate twice":     {data: `"\ud800\ud800\udc00"`, err: ErrLoneSurrogate, offset: 1},
+		"high surrogate then text": {data: `"\ud800abcdef"`, err: ErrLoneSurrogate, offset: 1},
+		"allowed lone surrogate":   {data: `"\ud800\u0041"`, opts: lenient, value: "\ufffdA"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseDocumentOptions([]byte(c.data), c.opts)
+			if c.err != nil {
+				var e *ParseError
+				if !errors.As(err, &e) || !errors.Is(err, c.err) {
+					t.Fatalf("expected error %v, received %v", c.err, err)
+				}
+				if e.Offset != c.offset {
+					t.Errorf("expected offset %d, received %d", c.offset, e.Offset)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			value, err := doc.stringValue(doc.root())
+			if err != nil {
+				t.Fatal("failed to decode string", err)
+			}
+			if value != c.value {
+				t.Errorf("expected value %q, received %q", c.value, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// UnsupportedTypeError signals that a Go type can't be marshaled.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "marshal: unsupported type: " + e.Type.String()
+}
+
+// UnsupportedValueError signals that a Go value can't be marshaled, like NaN
+// or a data structure containing a cycle.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "marshal: unsupported value: " + e.Str
+}
+
+// MarshalerError signals that a MarshalJSON or MarshalText method failed or
+// returned invalid JSON.
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+}
+
+func (e *MarshalerError) Error() string {
+	return "marshal: error calling MarshalJSON or MarshalText for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+func (e *MarshalerError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Marshal returns the JSON encoding of v. It follows the semantics of
+// encoding/json.Marshal:
+//
+//   - Structs become objects, using the field names or the names from "json"
+//     struct tags. Fields of embedded structs are promoted. The tag option
+//     "omitempty" skips false, zero, nil and empty values, the option
+//     "string" wraps scalar values in a JSON string.
+//   - Maps become objects with the keys sorted. Keys must be strings,
+//     integers or implement encoding.TextMarshaler.
+//   - Slices and arrays become arrays, except for byte slices, which become
+//     base64-encoded strings. Nil slices and maps become null.
+//   - Types implementing json.Marshaler or encoding.TextMarshaler encode
+//     themselves, the output of MarshalJSON is validated and compacted.
+//   - Cycles in the data structure are detected and reported as
+//     UnsupportedValueError instead of recursing infinitely.
+//
+// Deviations from encoding/json are:
+//
+//   - The characters "<", ">" and "&" as well as U+2028 and U+2029 are not
+//     escaped, since the output is not intended to be embedded into HTML.
+func Marshal(v interface{}) ([]byte, error) {
+	e := encoder{active: map[activeValue]bool{}}
+	return e.value(nil, reflect.ValueOf(v), false)
+}
+
+// encoder holds the state while encoding a Go value.
+type encoder struct {
+	active map[activeValue]bool // pointers, maps and slices currently being encoded
+}
+
+// activeValue identifies a value referenced by a pointer, map or slice, which
+// is used to detect cycles.
+type activeValue struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter marks the referenced value as being encoded. It fails if the value
+// is already being encoded, which means there is a cycle.
+func (e *encoder) enter(v reflect.Value) (activeValue, error) {
+	key := activeValue{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if e.active[key] {
+		return key, &UnsupportedValueError{Value: v, Str: "encountered a cycle via " + v.Type().String()}
+	}
+	e.active[key] = true
+	return key, nil
+}
+
+// value appends the JSON encoding of v to the buffer. With quoted, scalar
+// values are wrapped in a JSON string.
+func (e *encoder) value(buf []byte, v reflect.Value, quoted bool) ([]byte, error) {
+	if !v.IsValid() {
+		return append(buf, "null"...), nil
+	}
+
+	// Types with methods encode themselves. Methods with pointer receivers
+	// are only available if the value is addressable.
+	t := v.Type()
+	if t.Implements(marshalerType) || (v.CanAddr() && reflect.PtrTo(t).Implements(marshalerType)) {
+		return e.marshaler(buf, v)
+	}
+	if t.Implements(textMarshalerType) || (v.CanAddr() && reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return e.textMarshaler(buf, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if quoted {
+			buf = append(buf, '"')
+		}
+		buf = strconv.AppendBool(buf, v.Bool())
+		if quoted {
+			buf = append(buf, '"')
+		}
+		return buf, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if quoted {
+			buf = append(buf, '"')
+		}
+		buf = strconv.AppendInt(buf, v.Int(), 10)
+		if quoted {
+			buf = append(buf, '"')
+		}
+		return buf, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if quoted {
+			buf = append(buf, '"')
+		}
+		buf = strconv.AppendUint(buf, v.Uint(), 10)
+		if quoted {
+			buf = append(buf, '"')
+		}
+		return buf, nil
+
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, &UnsupportedValueError{Value: v, Str: strconv.FormatFloat(f, 'g', -1, t.Bits())}
+		}
+		if quoted {
+			buf = append(buf, '"')
+		}
+		buf = appendFloat(buf, f, t.Bits())
+		if quoted {
+			buf = append(buf, '"')
+		}
+		return buf, nil
+
+	case reflect.String:
+		if t == numberType {
+			s := v.String()
+			if s == "" {
+				s = "0"
+			}
+			if !isNumber(s) {
+				return nil, fmt.Errorf("marshal: invalid number literal %q", s)
+			}
+			if quoted {
+				return append(append(append(buf, '"'), s...), '"'), nil
+			}
+			return append(buf, s...), nil
+		}
+		if quoted {
+			return appendString(buf, string(appendString(nil, v.String()))), nil
+		}
+		return appendString(buf, v.String()), nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return append(buf, "null"...), nil
+		}
+		return e.value(buf, v.Elem(), false)
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return append(buf, "null"...), nil
+		}
+		key, err := e.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(e.active, key)
+		return e.value(buf, v.Elem(), quoted)
+
+	case reflect.Struct:
+		return e.structValue(buf, v)
+
+	case reflect.Map:
+		if v.IsNil() {
+			return append(buf, "null"...), nil
+		}
+		key, err := e.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(e.active, key)
+		return e.mapValue(buf, v)
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return append(buf, "null"...), nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 && !reflect.PtrTo(t.Elem()).Implements(marshalerType) && !reflect.PtrTo(t.Elem()).Implements(textMarshalerType) {
+			buf = append(buf, '"')
+			n := base64.StdEncoding.EncodedLen(v.Len())
+			start := len(buf)
+			buf = append(buf, make([]byte, n)...)
+			base64.StdEncoding.Encode(buf[start:], v.Bytes())
+			return append(buf, '"'), nil
+		}
+		key, err := e.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(e.active, key)
+		return e.arrayValue(buf, v)
+
+	case reflect.Array:
+		return e.arrayValue(buf, v)
+
+	default:
+		return nil, &UnsupportedTypeError{Type: t}
+	}
+}
+
+// appendFloat formats floats like encoding/json, which uses the same format
+// as ECMAScript.
+func appendFloat(buf []byte, f float64, bits int) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	buf = strconv.AppendFloat(buf, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf
+}
+
+// marshaler appends the output of a json.Marshaler.
+func (e *encoder) marshaler(buf []byte, v reflect.Value) ([]byte, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() || v.Kind() == reflect.Interface && v.IsNil() {
+		return append(buf, "null"...), nil
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() && !v.Type().Implements(marshalerType) {
+		v = v.Addr()
+	}
+	m := v.Interface().(json.Marshaler)
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return nil, &MarshalerError{Type: v.Type(), Err: err}
+	}
+	doc, err := ParseDocument(data)
+	if err == nil && doc.root() == 0 {
+		err = ErrEmptyDocument
+	}
+	if err != nil {
+		return nil, &MarshalerError{Type: v.Type(), Err: err}
+	}
+	return appendCompact(buf, doc), nil
+}
+
+// textMarshaler appends the output of an encoding.TextMarshaler as string.
+func (e *encoder) textMarshaler(buf []byte, v reflect.Value) ([]byte, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() || v.Kind() == reflect.Interface && v.IsNil() {
+		return append(buf, "null"...), nil
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() && !v.Type().Implements(textMarshalerType) {
+		v = v.Addr()
+	}
+	m := v.Interface().(encoding.TextMarshaler)
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, &MarshalerError{Type: v.Type(), Err: err}
+	}
+	return appendString(buf, string(text)), nil
+}
+
+// appendCompact appends the document without insignificant whitespace.
+func appendCompact(buf []byte, doc *Document) []byte {
+	root := doc.root()
+	for i := root; i <= doc.end(root); i++ {
+		e := doc.elements[i]
+		switch e.tpe {
+		case tObjectStart, tObjectEnd, tArrayStart, tArrayEnd, tComma, tColon:
+			buf = append(buf, doc.data[e.offset])
+		default:
+			buf = append(buf, doc.text(i)...)
+		}
+	}
+	return buf
+}
+
+func (e *encoder) structValue(buf []byte, v reflect.Value) ([]byte, error) {
+	fields := cachedTypeFields(v.Type())
+	buf = append(buf, '{')
+	first := true
+	for i := range fields.list {
+		f := &fields.list[i]
+
+		// walk down to the field, skipping it if an embedded pointer is nil
+		fv := v
+		for j, x := range f.index {
+			if j > 0 && fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv = reflect.Value{}
+					break
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(x)
+		}
+		if !fv.IsValid() || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = appendString(buf, f.name)
+		buf = append(buf, ':')
+		var err error
+		buf, err = e.value(buf, fv, f.asString)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+func (e *encoder) mapValue(buf []byte, v reflect.Value) ([]byte, error) {
+	// convert keys to strings and sort them
+	type member struct {
+		key   string
+		value reflect.Value
+	}
+	members := make([]member, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKeyString(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member{key: key, value: iter.Value()})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].key < members[j].key
+	})
+
+	buf = append(buf, '{')
+	for i, m := range members {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendString(buf, m.key)
+		buf = append(buf, ':')
+		var err error
+		buf, err = e.value(buf, m.value, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// mapKeyString converts a map key to an object member name.
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.Type().Implements(textMarshalerType) {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", &MarshalerError{Type: k.Type(), Err: err}
+		}
+		return string(text), nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	default:
+		return "", &UnsupportedTypeError{Type: k.Type()}
+	}
+}
+
+func (e *encoder) arrayValue(buf []byte, v reflect.Value) ([]byte, error) {
+	buf = append(buf, '[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		var err error
+		buf, err = e.value(buf, v.Index(i), false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, ']'), nil
+}
+
+// isEmptyValue tells whether the value is skipped by the "omitempty" option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+type marshalTest struct {
+	value interface{}
+	json  string
+}
+
+type testMarshalEmbedded struct {
+	E string `json:"e"`
+	A int    `json:"a"` // shadowed by testMarshalOuter.A
+}
+
+type testMarshalOuter struct {
+	*testMarshalEmbedded
+	A          string                  `json:"a"`
+	B          int                     `json:"b,omitempty"`
+	C          []int                   `json:"c,omitempty"`
+	N          int64                   `json:"n,string"`
+	S          string                  `json:"s,string"`
+	P          *float64                `json:"p"`
+	M          map[int]bool            `json:"m"`
+	Raw        json.RawMessage         `json:"raw"`
+	Any        interface{}             `json:"any"`
+	Text       testMarshalText         `json:"text"`
+	TMap       map[testMarshalText]int `json:"tmap,omitempty"`
+	Skip       bool                    `json:"-"`
+	Bytes      []byte                  `json:"bytes"`
+	unexported int
+}
+
+// testMarshalText is a type implementing encoding.TextMarshaler.
+type testMarshalText struct {
+	s string
+}
+
+func (t testMarshalText) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(t.s)), nil
+}
+
+// testBadMarshaler is a type returning invalid JSON from MarshalJSON.
+type testBadMarshaler struct{}
+
+func (testBadMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":}`), nil
+}
+
+// testCycle is a type that can contain itself.
+type testCycle struct {
+	Next *testCycle `json:"next"`
+}
+
+func TestMarshal(t *testing.T) {
+	cases := map[string]marshalTest{
+		"nil": {
+			value: nil,
+			json:  `null`,
+		},
+		"bool": {
+			value: true,
+			json:  `true`,
+		},
+		"int": {
+			value: int8(-12),
+			json:  `-12`,
+		},
+		"uint": {
+			value: uint64(math.MaxUint64),
+			json:  `18446744073709551615`,
+		},
+		"float 1": {
+			value: 1.5,
+			json:  `1.5`,
+		},
+		"float 2": {
+			value: 1e21,
+			json:  `1e+21`,
+		},
+		"float 3": {
+			value: float32(1e-7),
+			json:  `1e-7`,
+		},
+		"string": {
+			value: "a\"b\\c\n\x01ä<>",
+			json:  `"a\"b\\c\n\u0001ä<>"`,
+		},
+		"invalid utf-8": {
+			value: "a\xffb",
+			json:  `"a\ufffdb"`,
+		},
+		"number": {
+			value: json.Number("1.5e3"),
+			json:  `1.5e3`,
+		},
+		"bytes": {
+			value: []byte("hello"),
+			json:  `"aGVsbG8="`,
+		},
+		"nil slice": {
+			value: []int(nil),
+			json:  `null`,
+		},
+		"slice": {
+			value: []interface{}{1, "a", nil, []int{}},
+			json:  `[1,"a",null,[]]`,
+		},
+		"array": {
+			value: [2]bool{true, false},
+			json:  `[true,false]`,
+		},
+		"map": {
+			value: map[string]int{"b": 2, "a": 1, "c": 3},
+			json:  `{"a":1,"b":2,"c":3}`,
+		},
+		"int map": {
+			value: map[int]string{10: "x", 9: "y"},
+			json:  `{"10":"x","9":"y"}`,
+		},
+		"text map": {
+			value: map[testMarshalText]int{{s: "b"}: 2, {s: "a"}: 1},
+			json:  `{"A":1,"B":2}`,
+		},
+		"raw message": {
+			value: json.RawMessage(" [ 1 , { \"a\" : 2 } ] "),
+			json:  `[1,{"a":2}]`,
+		},
+		"struct empty": {
+			value: testMarshalOuter{},
+			json:  `{"a":"","n":"0","s":"\"\"","p":null,"m":null,"raw":null,"any":null,"text":"","bytes":null}`,
+		},
+		"struct": {
+			value: &testMarshalOuter{
+				testMarshalEmbedded: &testMarshalEmbedded{E: "e", A: 1},
+				A:                   "a",
+				B:                   2,
+				C:                   []int{3},
+				N:                   4,
+				S:                   "s",
+				P:                   newFloat(0.5),
+				M:                   map[int]bool{1: true},
+				Raw:                 json.RawMessage(`{"x": [1]}`),
+				Any:                 map[string]interface{}{"k": "v"},
+				Text:                testMarshalText{s: "t"},
+				TMap:                map[testMarshalText]int{{s: "k"}: 5},
+				Skip:                true,
+				Bytes:               []byte{1},
+				unexported:          1,
+			},
+			json: `{"e":"e","a":"a","b":2,"c":[3],"n":"4","s":"\"s\"","p":0.5,"m":{"1":true},"raw":{"x":[1]},"any":{"k":"v"},"text":"T","tmap":{"K":5},"bytes":"AQ=="}`,
+		},
+		"shared pointer": {
+			value: func() interface{} {
+				shared := &testCycle{}
+				return []*testCycle{shared, shared}
+			}(),
+			json: `[{"next":null},{"next":null}]`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data, err := Marshal(c.value)
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			if string(data) != c.json {
+				t.Log("expected JSON", c.json)
+				t.Log("received JSON", string(data))
+				t.Error("wrong JSON")
+			}
+		})
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	type roundTrip struct {
+		*EmbeddedInner
+		A   string         `json:"a"`
+		C   []int          `json:"c,omitempty"`
+		N   int64          `json:"n,string"`
+		P   *float64       `json:"p"`
+		M   map[int]string `json:"m"`
+		Any interface{}    `json:"any"`
+	}
+	value := roundTrip{
+		EmbeddedInner: &EmbeddedInner{B: 1},
+		A:             "x",
+		N:             42,
+		P:             newFloat(0.25),
+		M:             map[int]string{1: "one"},
+		Any:           []interface{}{"a", 1.5, true},
+	}
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	var result roundTrip
+	if err := Unmarshal(data, &result); err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	again, err := Marshal(result)
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	if equal, err := Equal(data, again); err != nil || !equal {
+		t.Log("first", string(data))
+		t.Log("second", string(again))
+		t.Error("round trip changed the value")
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	cycle := &testCycle{}
+	cycle.Next = cycle
+	if _, err := Marshal(cycle); err == nil {
+		t.Error("expected error for pointer cycle")
+	} else if _, ok := err.(*UnsupportedValueError); !ok {
+		t.Error("unexpected error for pointer cycle", err)
+	}
+
+	m := map[string]interface{}{}
+	m["self"] = m
+	if _, err := Marshal(m); err == nil {
+		t.Error("expected error for map cycle")
+	}
+
+	if _, err := Marshal(math.NaN()); err == nil {
+		t.Error("expected error for NaN")
+	}
+
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Error("expected error for channel")
+	} else if _, ok := err.(*UnsupportedTypeError); !ok {
+		t.Error("unexpected error for channel", err)
+	}
+
+	var me *MarshalerError
+	if _, err := Marshal(testBadMarshaler{}); !errors.As(err, &me) {
+		t.Error("expected error for invalid marshaler output", err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import (
+	"os"
+)
+
+// mapFile reads the file at the given path into memory, since memory mappings
+// are only used on Linux. It returns the data and a function to release it.
+func mapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// minParallelChunk is the minimum size of the chunks of the input that are
+// tokenized concurrently, smaller inputs are parsed on a single goroutine.
+const minParallelChunk = 1 << 20
+
+// maxGuessTokens is the number of tokens after which both guesses of how a
+// chunk starts are still plausible, so that only one of them is kept.
+const maxGuessTokens = 1 << 12
+
+// tokenChunk is the result of tokenizing a chunk of the input.
+type tokenChunk struct {
+	start  int           // offset where tokenizing started
+	end    int           // offset of the first token at or after the chunk end
+	tokens []JSONElement // tokens without parent
+	err    error         // error of the token at the end, if any
+}
+
+// tokenizeChunk tokenizes the data starting at the given offset, up to the
+// first token that starts at or after end. Tokens may extend beyond end.
+func tokenizeChunk(data []byte, start, end int, opts ParseOptions) *tokenChunk {
+	c := &tokenChunk{start: start, end: start}
+	for c.next(data, end, opts) {
+	}
+	return c
+}
+
+// next tokenizes the token at the end of the chunk. It returns false if the
+// chunk is complete or tokenizing failed.
+func (c *tokenChunk) next(data []byte, end int, opts ParseOptions) bool {
+	if c.end >= end || c.err != nil {
+		return false
+	}
+	tpe, size, err := nextToken(data, c.end, opts)
+	if err != nil {
+		c.err = err
+		return false
+	}
+	if tpe != tNone {
+		c.tokens = append(c.tokens, JSONElement{tpe: tpe, offset: c.end})
+	}
+	c.end += size
+	return true
+}
+
+// guessChunk speculatively tokenizes a chunk that doesn't start the input,
+// once assuming it starts outside of a string and once assuming it starts
+// inside one, which continues after the next quote. Both guesses are
+// tokenized side by side and the one that fails is dropped, which the wrong
+// one usually does within a few tokens. If both are still plausible after
+// maxGuessTokens, only the first is kept, so that wrong guesses take little
+// memory; when stitching the chunks together, a chunk without a guess in sync
+// is tokenized again.
+func guessChunk(data []byte, start, end int, opts ParseOptions) []*tokenChunk {
+	outside := &tokenChunk{start: start, end: start}
+	var inside *tokenChunk
+	// skip the rest of the string the chunk may start in
+	for cur := start; cur < end && inside == nil; cur++ {
+		switch data[cur] {
+		case '\\':
+			cur++
+		case '"':
+			inside = &tokenChunk{start: cur + 1, end: cur + 1}
+		}
+	}
+
+	guesses := []*tokenChunk{outside}
+	if inside != nil {
+		for len(outside.tokens)+len(inside.tokens) < maxGuessTokens {
+			more := outside.next(data, end, opts)
+			more = inside.next(data, end, opts) || more
+			if !more || outside.err != nil || inside.err != nil {
+				break
+			}
+		}
+		switch {
+		case outside.err != nil && inside.err != nil:
+			guesses = nil
+		case outside.err != nil:
+			guesses = []*tokenChunk{inside}
+		}
+	}
+	for _, guess := range guesses {
+		for guess.next(data, end, opts) {
+		}
+	}
+	return guesses
+}
+
+// boundary tells whether a token of the input starts at the given offset
+// according to the chunk, i.e. whether tokenizing from there yields the same
+// tokens as the chunk. Since tokenizing JSON only depends on the offset, this
+// holds for the offsets of the tokens and of the whitespace between them. It
+// returns the index of the first token at or after the offset.
+func (c *tokenChunk) boundary(data []byte, offset int, opts ParseOptions) (int, bool) {
+	if offset < c.start || offset > c.end {
+		return 0, false
+	}
+	i := sort.Search(len(c.tokens), func(i int) bool { return c.tokens[i].offset >= offset })
+	if i == 0 || i < len(c.tokens) && c.tokens[i].offset == offset {
+		return i, true
+	}
+	// the offset must be in the whitespace after the previous token
+	prev := c.tokens[i-1]
+	_, size, _ := nextToken(data, prev.offset, opts)
+	return i, prev.offset+size <= offset
+}
+
+// parseJSONParallel parses the data like parseJSONOptions, but splits it into
+// the given number of chunks that are tokenized concurrently.
+//
+// Where a chunk starts is unknown to its goroutine until the previous chunks
+// are tokenized, in particular whether it starts inside a string. So the
+// goroutines speculatively tokenize their chunk in two ways, see guessChunk.
+// When stitching the chunks together, the guess that is in sync with the end
+// of the previous chunk is used, or the chunk is tokenized again if none is.
+// A final pass over the tokens validates the structure and sets the parents,
+// releasing the tokens of each chunk once they are part of the tree.
+//
+// If tokenizing fails, the data is parsed again on a single goroutine, so that
+// the same error is reported as by parseJSONOptions.
+func parseJSONParallel(data []byte, opts ParseOptions, chunks int) ([]JSONElement, error) {
+	sequential := func() ([]JSONElement, error) {
+		opts.Parallelism = 0
+		return parseJSONOptions(data, opts)
+	}
+	if opts.Syntax != SyntaxJSON || chunks < 2 || len(data) < chunks {
+		// the tokens of JSON5 and JSONC depend on more than the offset
+		return sequential()
+	}
+
+	ends := make([]int, chunks)
+	guesses := make([][]*tokenChunk, chunks)
+	var wg sync.WaitGroup
+	for i := range guesses {
+		start := len(data) * i / chunks
+		ends[i] = len(data) * (i + 1) / chunks
+		wg.Add(1)
+		go func(i, start int) {
+			defer wg.Done()
+			if i == 0 {
+				guesses[i] = []*tokenChunk{tokenizeChunk(data, start, ends[i], opts)}
+			} else {
+				guesses[i] = guessChunk(data, start, ends[i], opts)
+			}
+		}(i, start)
+	}
+	wg.Wait()
+
+	// stitch the tokens of the chunks together
+	parts := [][]JSONElement{}
+	count := 1
+	cur := 0
+	for i, end := range ends {
+		if cur >= end {
+			// the chunk is covered by a token of the previous ones
+			continue
+		}
+		var chunk *tokenChunk
+		first := 0
+		for _, guess := range guesses[i] {
+			if index, ok := guess.boundary(data, cur, opts); ok {
+				chunk, first = guess, index
+				break
+			}
+		}
+		if chunk == nil {
+			chunk = tokenizeChunk(data, cur, end, opts)
+		}
+		if chunk.err != nil {
+			return sequential()
+		}
+		parts = append(parts, chunk.tokens[first:])
+		count += len(chunk.tokens) - first
+		cur = chunk.end
+		guesses[i] = nil
+	}
+
+	p := newIncrementalParser(data, opts, true)
+	p.res = append(make([]JSONElement, 0, count), p.res...)
+	for i, part := range parts {
+		for _, elem := range part {
+			p.token(elem)
+			if p.stopped {
+				return p.result()
+			}
+		}
+		parts[i] = nil
+	}
+	return p.result()
+}
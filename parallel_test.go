@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONParallel(t *testing.T) {
+	for name, c := range parseJSONCases {
+		t.Run(name, func(t *testing.T) {
+			expected, expectedErr := parseJSON(c.data)
+			for chunks := 2; chunks <= len(c.data) && chunks <= 16; chunks++ {
+				elements, err := parseJSONParallel(c.data, ParseOptions{}, chunks)
+				if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+					t.Fatalf("%d chunks: expected error %v, received %v", chunks, expectedErr, err)
+				}
+				if fmt.Sprint(elements) != fmt.Sprint(expected) {
+					t.Fatalf("%d chunks: expected elements %v, received %v", chunks, expected, elements)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJSONParallelSpeculation(t *testing.T) {
+	cases := map[string]struct {
+		data string
+		opts ParseOptions
+	}{
+		"json in strings":  {data: `["[1, 2]", "{\"a\": [true]}", {"b": "]}"}, null]`},
+		"escapes":          {data: `["\\", "\\\"", "\"\\\\\"", """, "x\\"]`},
+		"long string":      {data: `[1, "` + string(bytes.Repeat([]byte(`a", "`), 50)) + `", 2]`},
+		"whitespace":       {data: "  {  \"a\"  :\n\n  [  1 ,  2  ]  }  "},
+		"invalid in chunk": {data: `["abc", "def", tru, "ghi", "jkl"]`},
+		"invalid string":   {data: `["abc", "d\qf", "ghi", "jkl"]`},
+		"unterminated":     {data: `["abc", "def`},
+		"two values":       {data: `["abc", "def"] ["ghi"]`},
+		"too deep":         {data: `[[["a"], ["b"]], [["c"]]]`, opts: ParseOptions{MaxDepth: 2}},
+		"duplicate keys":   {data: `{"a": "x", "b": "y", "a": "z"}`, opts: ParseOptions{DuplicateKeys: DuplicateKeysKeepLast}},
+		"jsonc":            {data: "[\"a\", // \"b\n \"c\",]", opts: ParseOptions{Syntax: SyntaxJSONC}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := []byte(c.data)
+			expected, expectedErr := parseJSONOptions(data, c.opts)
+			for chunks := 2; chunks <= len(data); chunks++ {
+				elements, err := parseJSONParallel(data, c.opts, chunks)
+				if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+					t.Fatalf("%d chunks: expected error %v, received %v", chunks, expectedErr, err)
+				}
+				if fmt.Sprint(elements) != fmt.Sprint(expected) {
+					t.Fatalf("%d chunks: expected elements %v, received %v", chunks, expected, elements)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJSONParallelGuessLimit(t *testing.T) {
+	// strings containing tokens, so that both guesses stay plausible
+	data := []byte(`[` + strings.Repeat(`"1, 2, [3], {}, ", `, maxGuessTokens) + `4]`)
+	if guesses := guessChunk(data, len(data)/2, len(data), ParseOptions{}); len(guesses) != 1 {
+		t.Errorf("expected 1 guess to be kept, received %d", len(guesses))
+	}
+
+	expected, err := parseJSON(data)
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	for _, chunks := range []int{2, 3, 4, 7} {
+		elements, err := parseJSONParallel(data, ParseOptions{}, chunks)
+		if err != nil {
+			t.Fatalf("%d chunks: unexpected failure %v", chunks, err)
+		}
+		if fmt.Sprint(elements) != fmt.Sprint(expected) {
+			t.Fatalf("%d chunks: elements differ from sequential parsing", chunks)
+		}
+	}
+}
+
+func TestParseDocumentParallelism(t *testing.T) {
+	data := benchTwitter(4000)
+	if len(data) < 3*minParallelChunk {
+		t.Fatalf("test input of %d bytes too small", len(data))
+	}
+	expected, err := ParseDocument(data)
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	doc, err := ParseDocumentOptions(data, ParseOptions{Parallelism: 4})
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	if fmt.Sprint(doc.elements) != fmt.Sprint(expected.elements) {
+		t.Error("elements differ from sequential parsing")
+	}
+
+	// the error is the one of sequential parsing
+	data[len(data)/2] = '\x01'
+	_, expectedErr := ParseDocument(data)
+	if _, err := ParseDocumentOptions(data, ParseOptions{Parallelism: 4}); fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+		t.Errorf("expected error %v, received %v", expectedErr, err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPointer signals a malformed JSON Pointer or one that doesn't
+// refer to a value in the document.
+var ErrInvalidPointer = errors.New("invalid JSON pointer")
+
+// pointerEscaper escapes a reference token for use in a JSON Pointer.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// pointerUnescaper reverses the escaping of pointerEscaper.
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// appendPointer appends a reference token to a JSON Pointer (RFC 6901).
+func appendPointer(pointer, token string) string {
+	return pointer + "/" + pointerEscaper.Replace(token)
+}
+
+// appendPointerIndex appends an array index to a JSON Pointer.
+func appendPointerIndex(pointer string, index int) string {
+	return pointer + "/" + strconv.Itoa(index)
+}
+
+// resolvePointer returns the index of the value that the JSON Pointer refers
+// to, relative to the value at the given index.
+func (d *Document) resolvePointer(index int, pointer string) (int, error) {
+	if pointer == "" {
+		return index, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return 0, ErrInvalidPointer
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = pointerUnescaper.Replace(token)
+		switch d.elements[index].tpe {
+		case tObjectStart:
+			members, err := d.members(index)
+			if err != nil {
+				return 0, err
+			}
+			next, ok := members[token]
+			if !ok {
+				return 0, ErrInvalidPointer
+			}
+			index = next
+		case tArrayStart:
+			// array indices are decimal numbers without leading zeros
+			if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+				return 0, ErrInvalidPointer
+			}
+			i, err := strconv.Atoi(token)
+			if err != nil {
+				return 0, ErrInvalidPointer
+			}
+			children := d.children(index)
+			if i >= len(children) {
+				return 0, ErrInvalidPointer
+			}
+			index = children[i]
+		default:
+			return 0, ErrInvalidPointer
+		}
+	}
+	return index, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+)
+
+// ParseDocumentRecover parses the data like ParseDocumentOptions, but instead
+// of failing at the first error, it reports all of them together with a
+// best-effort document. This is meant for tools like linters and editors.
+//
+// After an error, the parser drops the offending array element or object
+// member and skips the input up to the next comma or closing token at the
+// same depth. Arrays and objects that are still open at the end of the input
+// are closed by appending the missing closing tokens to the data of the
+// document. The document is nil if the input can't be decoded at all.
+func ParseDocumentRecover(data []byte, opts ParseOptions) (*Document, []*ParseError) {
+	in, opts, err := prepareInput(data, opts)
+	if err != nil {
+		var e *ParseError
+		if !errors.As(err, &e) {
+			e = &ParseError{Offset: 0, Err: err}
+		}
+		return nil, []*ParseError{e}
+	}
+	text, elements, errs := parseJSONRecover(in.text, opts)
+	for i, e := range errs {
+		errs[i] = &ParseError{Offset: in.originalOffset(e.Offset), Err: e.Err}
+	}
+	return newDocument(text, elements, opts.Syntax), errs
+}
+
+// parseJSONRecover parses the data like parseJSONOptions, but recovers from
+// errors as described for ParseDocumentRecover. It returns the data with the
+// missing closing tokens appended, the syntax tree and the errors.
+func parseJSONRecover(data []byte, opts ParseOptions) ([]byte, []JSONElement, []*ParseError) {
+	p, text := recoverTree(data, opts)
+	return text, p.res, p.errs
+}
+
+// recoverTree runs a recovering parser over the data and returns it together
+// with the data with the missing closing tokens appended.
+func recoverTree(data []byte, opts ParseOptions) (*incrementalParser, []byte) {
+	p := newIncrementalParser(data, opts, false)
+	for cur := 0; cur != len(data) && !p.stopped; {
+		tpe, size, err := nextToken(data, cur, opts)
+		if err != nil {
+			if !p.skipping {
+				p.tokenError(cur, err)
+			}
+			cur += skipInvalid(data, cur)
+			continue
+		}
+		if tpe != tNone {
+			p.token(JSONElement{tpe: tpe, offset: cur})
+		}
+		cur += size
+	}
+	text := p.finish()
+
+	res, err := applyDuplicateKeyPolicy(text, p.res, opts)
+	if err != nil {
+		var e *ParseError
+		if errors.As(err, &e) {
+			p.errs = append(p.errs, e)
+			p.hints = append(p.hints, "")
+		}
+	} else {
+		p.res = res
+	}
+	return p, text
+}
+
+// skipInvalid returns the number of bytes to skip after an invalid token at
+// the given offset. Strings are skipped up to the closing quote on the same
+// line, anything else up to the next character that may start a token.
+func skipInvalid(data []byte, cur int) int {
+	res := 1
+	if q := data[cur]; q == '"' || q == '\'' {
+		for cur+res != len(data) {
+			switch data[cur+res] {
+			case q:
+				return res + 1
+			case '\\':
+				if cur+res+1 != len(data) && data[cur+res+1] != '\n' {
+					res++
+				}
+			case '\n', '\r':
+				return res
+			}
+			res++
+		}
+		return res
+	}
+	for cur+res != len(data) {
+		switch data[cur+res] {
+		case ' ', '\t', '\n', '\r', '{', '}', '[', ']', ',', ':', '"', '\'', '/':
+			return res
+		}
+		res++
+	}
+	return res
+}
+
+// finish closes the arrays and objects that are still open at the end of the
+// input. It returns the data with the closing tokens appended.
+func (p *incrementalParser) finish() []byte {
+	if p.context == 0 {
+		return p.data
+	}
+	p.report(len(p.data), ErrUnexpectedEOF)
+	text := p.data[:len(p.data):len(p.data)]
+	for p.context != 0 {
+		elem := JSONElement{tpe: tArrayEnd, offset: len(text)}
+		text = append(text, ']')
+		if p.res[p.context].tpe == tObjectStart {
+			elem.tpe = tObjectEnd
+			text[len(text)-1] = '}'
+		}
+		if p.state == rsNext && !p.opts.Syntax.trailingCommas() || p.state == rsColon || p.state == rsValue {
+			p.drop()
+		}
+		p.state = rsSeparator
+		p.close(elem)
+	}
+	return text
+}
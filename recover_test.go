@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type recoverTest struct {
+	data     string
+	opts     ParseOptions
+	expected string // best-effort document
+	offsets  []int  // offsets of the expected errors
+	err      error  // error at the first offset, ErrInvalidStructure if nil
+}
+
+func TestParseDocumentRecover(t *testing.T) {
+	cases := map[string]recoverTest{
+		"valid":                 {data: `{"a": [1, 2], "b": null}`, expected: `{"a": [1, 2], "b": null}`},
+		"empty":                 {data: ``, expected: ``},
+		"invalid token":         {data: `[1, tru, 3]`, expected: `[1, 3]`, offsets: []int{4}, err: ErrInvalidToken},
+		"invalid first":         {data: `[tru, 2]`, expected: `[2]`, offsets: []int{1}, err: ErrInvalidToken},
+		"invalid string":        {data: `["a\qb", "c"]`, expected: `["c"]`, offsets: []int{1}, err: ErrInvalidToken},
+		"unterminated string":   {data: "[\"abc, 2,\n3]", expected: `[]`, offsets: []int{1}, err: ErrInvalidToken},
+		"missing comma":         {data: `[1 2, 3]`, expected: `[1, 3]`, offsets: []int{3}},
+		"double comma":          {data: `[1,, 2]`, expected: `[1, 2]`, offsets: []int{3}},
+		"leading comma":         {data: `[, 1]`, expected: `[1]`, offsets: []int{1}},
+		"trailing comma":        {data: `[1, 2,]`, expected: `[1, 2]`, offsets: []int{5}},
+		"missing value":         {data: `{"a": , "b": 2}`, expected: `{"b": 2}`, offsets: []int{6}},
+		"missing colon":         {data: `{"a" 1, "b": 2}`, expected: `{"b": 2}`, offsets: []int{5}},
+		"missing member name":   {data: `{1: 2, "b": 2}`, expected: `{"b": 2}`, offsets: []int{1}},
+		"missing value at end":  {data: `{"a": 1, "b":}`, expected: `{"a": 1}`, offsets: []int{13}},
+		"nested error":          {data: `{"a": [1, {"b" 2}], "c": 3}`, expected: `{"a": [1, {}], "c": 3}`, offsets: []int{15}},
+		"skip nested":           {data: `[1 {"a": [2, 3]}, 4]`, expected: `[1, 4]`, offsets: []int{3}},
+		"wrong closing":         {data: `[1, 2}]`, expected: `[1, 2]`, offsets: []int{5}},
+		"stray closing":         {data: `]1`, expected: `1`, offsets: []int{0}},
+		"multiple values":       {data: `1 2 3`, expected: `1`, offsets: []int{2}},
+		"missing closing":       {data: `{"a": [1, {"b": 2`, expected: `{"a": [1, {"b": 2}]}`, offsets: []int{17}, err: ErrUnexpectedEOF},
+		"missing closing value": {data: `{"a": [1, {"b":`, expected: `{"a": [1, {}]}`, offsets: []int{15}, err: ErrUnexpectedEOF},
+		"all errors":            {data: `[1 2, tru, {"a" 3}, [,], 4,]`, expected: `[1, {}, [], 4]`, offsets: []int{3, 6, 16, 21, 26}},
+		"too deep": {
+			data:     `[1, [[2]], 3]`,
+			opts:     ParseOptions{MaxDepth: 2},
+			expected: `[1, [], 3]`,
+			offsets:  []int{5},
+			err:      ErrTooDeep,
+		},
+		"too many members": {
+			data:     `{"a": 1, "b": 2, "c": 3}`,
+			opts:     ParseOptions{MaxMembers: 1},
+			expected: `{"a": 1}`,
+			offsets:  []int{9, 17},
+			err:      ErrTooManyMembers,
+		},
+		"duplicate keys": {
+			data:     `{"a": 1, "a": 2, "b" 3}`,
+			opts:     ParseOptions{DuplicateKeys: DuplicateKeysReject},
+			expected: `{"a": 1, "a": 2}`,
+			offsets:  []int{21, 9},
+		},
+		"json5": {
+			data:     `{a: 1, b: c, d: 2,}`,
+			opts:     ParseOptions{Syntax: SyntaxJSON5},
+			expected: `{"a": 1, "d": 2}`,
+			offsets:  []int{10},
+		},
+		"jsonc": {
+			data:     "[1, // one\n2 3]",
+			opts:     ParseOptions{Syntax: SyntaxJSONC},
+			expected: `[1, 2]`,
+			offsets:  []int{13},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			doc, errs := ParseDocumentRecover([]byte(c.data), c.opts)
+			if doc == nil {
+				t.Fatal("missing document")
+			}
+			offsets := []int{}
+			for _, e := range errs {
+				offsets = append(offsets, e.Offset)
+			}
+			if c.offsets == nil {
+				c.offsets = []int{}
+			}
+			if fmt.Sprint(offsets) != fmt.Sprint(c.offsets) {
+				t.Errorf("expected errors at %v, received %v", c.offsets, errs)
+			}
+			if c.err == nil {
+				c.err = ErrInvalidStructure
+			}
+			if len(errs) > 0 && !errors.Is(errs[0], c.err) {
+				t.Errorf("expected error %v, received %v", c.err, errs[0])
+			}
+
+			expected, err := ParseDocument([]byte(c.expected))
+			if err != nil {
+				t.Fatal("invalid expected document", err)
+			}
+			if !EqualDocuments(doc, expected) {
+				t.Errorf("expected %s, received %s", c.expected, appendCompact(nil, doc))
+			}
+			if _, err := parseJSONOptions(doc.data, c.opts); len(errs) == 0 && err != nil {
+				t.Error("valid input rejected by parseJSON", err)
+			}
+		})
+	}
+}
+
+func TestParseDocumentRecoverEncoding(t *testing.T) {
+	doc, errs := ParseDocumentRecover(utf16LE(`[1 2]`), ParseOptions{})
+	if len(errs) != 1 || errs[0].Offset != 6 {
+		t.Errorf("expected error at offset 6, received %v", errs)
+	}
+	if doc == nil || string(doc.data) != `[1 2]` {
+		t.Errorf("expected document for [1 2], received %v", doc)
+	}
+
+	doc, errs = ParseDocumentRecover([]byte{0xef, 0xbb, 0xbf, '1'}, ParseOptions{RejectBOM: true})
+	if doc != nil || len(errs) != 1 || !errors.Is(errs[0], ErrByteOrderMark) {
+		t.Errorf("expected ErrByteOrderMark only, received %v", errs)
+	}
+}
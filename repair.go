@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Fix describes a change that Repair made to the input.
+type Fix struct {
+	Offset int    // offset of the change in the input data
+	Reason string // description of the change, e.g. "removed trailing comma"
+}
+
+func (f Fix) String() string {
+	return fmt.Sprintf("offset %d: %s", f.Offset, f.Reason)
+}
+
+// Repair fixes common breakage of almost-JSON, like it is produced by scripts
+// and language models:
+//
+//   - missing closing brackets and braces at the end of the input
+//   - trailing commas
+//   - single-quoted strings
+//   - unquoted member names
+//   - the Python literals True, False and None
+//   - control characters inside strings
+//
+// It returns the repaired data together with the applied fixes. If the
+// result still isn't valid JSON, it fails with the error of parsing it.
+func Repair(data []byte) ([]byte, []Fix, error) {
+	r := &repairer{data: data, out: make([]byte, 0, len(data))}
+	for cur := 0; cur < len(data); {
+		cur = r.next(cur)
+	}
+	for i := len(r.open) - 1; i >= 0; i-- {
+		closing := byte(']')
+		if r.open[i] == '{' {
+			closing = '}'
+		}
+		r.fix(len(data), "added missing "+string(closing))
+		r.out = append(r.out, closing)
+	}
+
+	if _, err := ParseDocument(r.out); err != nil {
+		return nil, r.fixes, err
+	}
+	return r.out, r.fixes, nil
+}
+
+// repairer is the state of Repair.
+type repairer struct {
+	data  []byte
+	out   []byte // repaired data
+	fixes []Fix
+	open  []byte // opening brackets and braces, innermost last
+}
+
+// fix records a change at the given offset.
+func (r *repairer) fix(offset int, reason string) {
+	r.fixes = append(r.fixes, Fix{Offset: offset, Reason: reason})
+}
+
+// next repairs the token at the given offset and returns the offset after it.
+func (r *repairer) next(cur int) int {
+	c := r.data[cur]
+	switch c {
+	case '"', '\'':
+		return r.string(cur)
+	case '{', '[':
+		r.open = append(r.open, c)
+	case '}', ']':
+		if n := len(r.open); n > 0 && r.open[n-1] == c-2 {
+			// "[" and "{" precede "]" and "}" by two in ASCII
+			r.open = r.open[:n-1]
+		}
+	case ',':
+		if next := r.skipSpace(cur + 1); next == len(r.data) || r.data[next] == '}' || r.data[next] == ']' {
+			r.fix(cur, "removed trailing comma")
+			return cur + 1
+		}
+	default:
+		if rn, _ := utf8.DecodeRune(r.data[cur:]); isIdentifierStart(rn) {
+			return r.identifier(cur)
+		}
+	}
+	r.out = append(r.out, c)
+	return cur + 1
+}
+
+// skipSpace returns the offset of the first non-whitespace byte starting at
+// the given offset.
+func (r *repairer) skipSpace(cur int) int {
+	for cur < len(r.data) {
+		switch r.data[cur] {
+		case ' ', '\t', '\n', '\r':
+			cur++
+		default:
+			return cur
+		}
+	}
+	return cur
+}
+
+// identifier repairs unquoted member names and Python literals.
+func (r *repairer) identifier(cur int) int {
+	end := cur
+	for end < len(r.data) {
+		rn, size := utf8.DecodeRune(r.data[end:])
+		if !isIdentifierPart(rn) {
+			break
+		}
+		end += size
+	}
+	word := string(r.data[cur:end])
+
+	if next := r.skipSpace(end); next < len(r.data) && r.data[next] == ':' && len(r.open) > 0 && r.open[len(r.open)-1] == '{' {
+		r.fix(cur, "quoted member name "+word)
+		r.out = append(r.out, '"')
+		r.out = append(r.out, word...)
+		r.out = append(r.out, '"')
+		return end
+	}
+	replacement, ok := map[string]string{"True": "true", "False": "false", "None": "null"}[word]
+	if ok {
+		r.fix(cur, "replaced "+word+" with "+replacement)
+		word = replacement
+	}
+	r.out = append(r.out, word...)
+	return end
+}
+
+// string repairs the string at the given offset, converting single quotes and
+// escaping control characters. A missing closing quote at the end of the
+// input is added.
+func (r *repairer) string(cur int) int {
+	quote := r.data[cur]
+	if quote == '\'' {
+		r.fix(cur, "replaced single quotes")
+	}
+	r.out = append(r.out, '"')
+	for i := cur + 1; i < len(r.data); i++ {
+		c := r.data[i]
+		switch {
+		case c == quote:
+			r.out = append(r.out, '"')
+			return i + 1
+		case c == '\\' && i+1 < len(r.data):
+			if quote == '\'' && r.data[i+1] == '\'' {
+				// no need to escape single quotes anymore
+				r.out = append(r.out, '\'')
+			} else {
+				r.out = append(r.out, c, r.data[i+1])
+			}
+			i++
+		case c == '"':
+			// double quotes inside single-quoted strings
+			r.out = append(r.out, '\\', '"')
+		case c < 0x20:
+			r.fix(i, "escaped control character")
+			r.out = appendControl(r.out, c)
+		default:
+			r.out = append(r.out, c)
+		}
+	}
+	r.fix(len(r.data), "added missing closing quote")
+	r.out = append(r.out, '"')
+	return len(r.data)
+}
+
+// appendControl appends the escape sequence of a control character.
+func appendControl(buf []byte, c byte) []byte {
+	switch c {
+	case '\b':
+		return append(buf, '\\', 'b')
+	case '\f':
+		return append(buf, '\\', 'f')
+	case '\n':
+		return append(buf, '\\', 'n')
+	case '\r':
+		return append(buf, '\\', 'r')
+	case '\t':
+		return append(buf, '\\', 't')
+	default:
+		return append(buf, fmt.Sprintf("\\u%04x", c)...)
+	}
+}
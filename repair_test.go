@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	cases := map[string]struct {
+		data     string
+		expected string
+		offsets  []int // offsets of the expected fixes
+	}{
+		"valid":             {data: `{"a": [1, 2], "b": null}`, expected: `{"a": [1, 2], "b": null}`},
+		"missing closing":   {data: `{"a": [1, {"b": 2`, expected: `{"a": [1, {"b": 2}]}`, offsets: []int{17, 17, 17}},
+		"trailing comma":    {data: `[1, 2, ]`, expected: `[1, 2 ]`, offsets: []int{5}},
+		"trailing at end":   {data: `{"a": 1,`, expected: `{"a": 1}`, offsets: []int{7, 8}},
+		"single quotes":     {data: `['a', 'b"c', 'd\'e']`, expected: `["a", "b\"c", "d'e"]`, offsets: []int{1, 6, 13}},
+		"unquoted keys":     {data: `{a: 1, $b_2 : {c: 3}}`, expected: `{"a": 1, "$b_2" : {"c": 3}}`, offsets: []int{1, 7, 15}},
+		"python literals":   {data: `[True, False, None, true]`, expected: `[true, false, null, true]`, offsets: []int{1, 7, 14}},
+		"python key":        {data: `{None: None}`, expected: `{"None": null}`, offsets: []int{1, 7}},
+		"control chars":     {data: "[\"a\tb\nc\x01\"]", expected: `["a\tb\nc\u0001"]`, offsets: []int{3, 5, 7}},
+		"unterminated":      {data: `["abc`, expected: `["abc"]`, offsets: []int{5, 5}},
+		"keyword in string": {data: `["True", "a,]"]`, expected: `["True", "a,]"]`},
+		"all": {
+			data:     "{name: 'x',\n ok: True, list: [1, 2,],",
+			expected: "{\"name\": \"x\",\n \"ok\": true, \"list\": [1, 2]}",
+			offsets:  []int{1, 7, 13, 17, 23, 34, 36, 37},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			repaired, fixes, err := Repair([]byte(c.data))
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			if string(repaired) != c.expected {
+				t.Errorf("expected %s, received %s", c.expected, repaired)
+			}
+			offsets := []int{}
+			for _, fix := range fixes {
+				offsets = append(offsets, fix.Offset)
+			}
+			if c.offsets == nil {
+				c.offsets = []int{}
+			}
+			if fmt.Sprint(offsets) != fmt.Sprint(c.offsets) {
+				t.Errorf("expected fixes at %v, received %v", c.offsets, fixes)
+			}
+		})
+	}
+}
+
+func TestRepairInvalid(t *testing.T) {
+	cases := map[string]struct {
+		data string
+		err  error
+	}{
+		"unknown identifier": {data: `[undefined]`, err: ErrInvalidToken},
+		"missing value":      {data: `{"a":`, err: ErrInvalidStructure},
+		"wrong closing":      {data: `[1}`, err: ErrInvalidStructure},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			repaired, _, err := Repair([]byte(c.data))
+			if !errors.Is(err, c.err) {
+				t.Errorf("expected error %v, received %v", c.err, err)
+			}
+			if repaired != nil {
+				t.Errorf("expected no data, received %s", repaired)
+			}
+		})
+	}
+}
@@ -0,0 +1,685 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrEmptyDocument signals that a document doesn't contain any value.
+var ErrEmptyDocument = errors.New("empty document")
+
+// SchemaError signals that a schema document is not a valid schema.
+type SchemaError struct {
+	// KeywordLocation is a JSON Pointer to the offending part of the schema.
+	KeywordLocation string
+	// Message describes the problem.
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("invalid schema at %q: %s", e.KeywordLocation, e.Message)
+}
+
+// ValidationError describes a single violation of a schema by an instance.
+type ValidationError struct {
+	// InstanceLocation is a JSON Pointer to the offending value.
+	InstanceLocation string
+	// KeywordLocation is a JSON Pointer to the violated keyword in the schema.
+	// This is the path taken during evaluation, so it includes "$ref".
+	KeywordLocation string
+	// Message describes the violation.
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%q: %s (%q)", e.InstanceLocation, e.Message, e.KeywordLocation)
+}
+
+// ValidationErrors lists all violations of a schema by an instance.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Schema is a compiled JSON Schema (draft 2020-12).
+//
+// Supported are the keywords "type", "properties", "required", "items",
+// "prefixItems", "enum", "const", "minimum", "maximum", "exclusiveMinimum",
+// "exclusiveMaximum", "multipleOf", "minLength", "maxLength", "pattern",
+// "$ref", "$defs", "$anchor", "allOf", "anyOf", "oneOf" and "not". Any other
+// keywords are ignored. References must be local to the schema document,
+// i.e. either JSON Pointers ("#/$defs/name") or anchors ("#name").
+//
+// Patterns use the syntax of Go's regexp package instead of ECMA-262, which
+// is sufficient for most patterns found in practice.
+type Schema struct {
+	doc  *Document
+	root *schemaNode
+}
+
+// schemaNode is a compiled (sub)schema.
+type schemaNode struct {
+	// boolean schemas ("true" or "false") don't have any keywords
+	isBool    bool
+	boolValue bool
+
+	types            []string
+	properties       map[string]*schemaNode
+	required         []string
+	prefixItems      []*schemaNode
+	items            *schemaNode
+	enum             []int // indices of the values in the schema document
+	constant         int   // index of the value in the schema document, zero if absent
+	minimum          *decimal
+	maximum          *decimal
+	exclusiveMinimum *decimal
+	exclusiveMaximum *decimal
+	multipleOf       *decimal
+	multipleOfRat    *big.Rat
+	minLength        int // -1 if absent
+	maxLength        int // -1 if absent
+	pattern          *regexp.Regexp
+	ref              string
+	refNode          *schemaNode
+	allOf            []*schemaNode
+	anyOf            []*schemaNode
+	oneOf            []*schemaNode
+	not              *schemaNode
+}
+
+// schemaCompiler holds the state while compiling a schema document.
+type schemaCompiler struct {
+	doc     *Document
+	nodes   map[int]*schemaNode // compiled nodes by element index
+	anchors map[string]int      // element index by anchor name
+	refs    []refLocation       // references to resolve
+}
+
+// refLocation remembers a node with a reference and where it is.
+type refLocation struct {
+	node     *schemaNode
+	location string
+}
+
+// CompileSchema parses and compiles the schema in the given data.
+func CompileSchema(data []byte) (*Schema, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return CompileSchemaDocument(doc)
+}
+
+// CompileSchemaDocument compiles the schema in the given document.
+func CompileSchemaDocument(doc *Document) (*Schema, error) {
+	root := doc.root()
+	if root == 0 {
+		return nil, ErrEmptyDocument
+	}
+
+	c := schemaCompiler{
+		doc:     doc,
+		nodes:   map[int]*schemaNode{},
+		anchors: map[string]int{},
+	}
+	node, err := c.compile(root, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve references. This can compile further subschemas which in turn
+	// contain references, so the list can grow while iterating it.
+	for i := 0; i < len(c.refs); i++ {
+		r := c.refs[i]
+		target, err := c.resolve(root, r)
+		if err != nil {
+			return nil, err
+		}
+		r.node.refNode = target
+	}
+
+	return &Schema{doc: doc, root: node}, nil
+}
+
+// resolve finds and compiles the target of a reference.
+func (c *schemaCompiler) resolve(root int, r refLocation) (*schemaNode, error) {
+	fail := func(msg string) (*schemaNode, error) {
+		return nil, &SchemaError{KeywordLocation: appendPointer(r.location, "$ref"), Message: msg}
+	}
+
+	ref := r.node.ref
+	if !strings.HasPrefix(ref, "#") {
+		return fail("only local references are supported")
+	}
+	fragment := ref[1:]
+
+	// plain name fragments refer to anchors
+	if fragment != "" && !strings.HasPrefix(fragment, "/") {
+		index, ok := c.anchors[fragment]
+		if !ok {
+			return fail(fmt.Sprintf("unknown anchor %q", fragment))
+		}
+		return c.nodes[index], nil
+	}
+
+	// JSON Pointers are percent-encoded in URI fragments
+	pointer, err := unescapeFragment(fragment)
+	if err != nil {
+		return fail(err.Error())
+	}
+	index, err := c.doc.resolvePointer(root, pointer)
+	if err != nil {
+		return fail(fmt.Sprintf("unresolvable reference %q", ref))
+	}
+	return c.compile(index, pointer)
+}
+
+// unescapeFragment decodes percent-encoded characters in a URI fragment.
+func unescapeFragment(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", errors.New("invalid percent encoding")
+		}
+		hi, ok1 := hexValue(s[i+1])
+		lo, ok2 := hexValue(s[i+2])
+		if !ok1 || !ok2 {
+			return "", errors.New("invalid percent encoding")
+		}
+		b.WriteByte(hi<<4 | lo)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// hexValue returns the value of a single hex digit.
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	default:
+		return 0, false
+	}
+}
+
+// compile compiles the schema at the given element index. The location is the
+// JSON Pointer of the schema within the schema document.
+func (c *schemaCompiler) compile(index int, location string) (*schemaNode, error) {
+	if node, ok := c.nodes[index]; ok {
+		return node, nil
+	}
+
+	fail := func(keyword, msg string) (*schemaNode, error) {
+		return nil, &SchemaError{KeywordLocation: appendPointer(location, keyword), Message: msg}
+	}
+
+	node := &schemaNode{minLength: -1, maxLength: -1}
+	switch c.doc.elements[index].tpe {
+	case tBool:
+		node.isBool = true
+		node.boolValue = c.doc.boolValue(index)
+		c.nodes[index] = node
+		return node, nil
+	case tObjectStart:
+		// register before compiling subschemas, so recursion terminates
+		c.nodes[index] = node
+	default:
+		return nil, &SchemaError{KeywordLocation: location, Message: "schema must be an object or a boolean"}
+	}
+
+	children := c.doc.children(index)
+	for i := 0; i+1 < len(children); i += 2 {
+		keyword, err := c.doc.stringValue(children[i])
+		if err != nil {
+			return nil, err
+		}
+		value := children[i+1]
+		loc := appendPointer(location, keyword)
+		tpe := c.doc.elements[value].tpe
+
+		switch keyword {
+		case "type":
+			switch tpe {
+			case tString:
+				s, _ := c.doc.stringValue(value)
+				node.types = []string{s}
+			case tArrayStart:
+				for _, v := range c.doc.children(value) {
+					if c.doc.elements[v].tpe != tString {
+						return fail(keyword, "type names must be strings")
+					}
+					s, _ := c.doc.stringValue(v)
+					node.types = append(node.types, s)
+				}
+			default:
+				return fail(keyword, "must be a string or an array of strings")
+			}
+			for _, t := range node.types {
+				switch t {
+				case "null", "boolean", "object", "array", "number", "string", "integer":
+				default:
+					return fail(keyword, fmt.Sprintf("unknown type %q", t))
+				}
+			}
+
+		case "properties", "$defs":
+			if tpe != tObjectStart {
+				return fail(keyword, "must be an object")
+			}
+			props := c.doc.children(value)
+			if keyword == "properties" {
+				node.properties = make(map[string]*schemaNode, len(props)/2)
+			}
+			for j := 0; j+1 < len(props); j += 2 {
+				name, err := c.doc.stringValue(props[j])
+				if err != nil {
+					return nil, err
+				}
+				sub, err := c.compile(props[j+1], appendPointer(loc, name))
+				if err != nil {
+					return nil, err
+				}
+				if keyword == "properties" {
+					node.properties[name] = sub
+				}
+			}
+
+		case "required":
+			if tpe != tArrayStart {
+				return fail(keyword, "must be an array of strings")
+			}
+			for _, v := range c.doc.children(value) {
+				if c.doc.elements[v].tpe != tString {
+					return fail(keyword, "must be an array of strings")
+				}
+				s, _ := c.doc.stringValue(v)
+				node.required = append(node.required, s)
+			}
+
+		case "items", "not":
+			sub, err := c.compile(value, loc)
+			if err != nil {
+				return nil, err
+			}
+			if keyword == "items" {
+				node.items = sub
+			} else {
+				node.not = sub
+			}
+
+		case "prefixItems", "allOf", "anyOf", "oneOf":
+			if tpe != tArrayStart {
+				return fail(keyword, "must be an array of schemas")
+			}
+			subs := []*schemaNode{}
+			for j, v := range c.doc.children(value) {
+				sub, err := c.compile(v, appendPointerIndex(loc, j))
+				if err != nil {
+					return nil, err
+				}
+				subs = append(subs, sub)
+			}
+			switch keyword {
+			case "prefixItems":
+				node.prefixItems = subs
+			case "allOf":
+				node.allOf = subs
+			case "anyOf":
+				node.anyOf = subs
+			case "oneOf":
+				node.oneOf = subs
+			}
+
+		case "enum":
+			if tpe != tArrayStart {
+				return fail(keyword, "must be an array")
+			}
+			node.enum = c.doc.children(value)
+
+		case "const":
+			node.constant = value
+
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			if tpe != tNumber {
+				return fail(keyword, "must be a number")
+			}
+			x, ok := parseDecimal(c.doc.numberText(value))
+			if !ok {
+				return fail(keyword, "must be a number")
+			}
+			switch keyword {
+			case "minimum":
+				node.minimum = &x
+			case "maximum":
+				node.maximum = &x
+			case "exclusiveMinimum":
+				node.exclusiveMinimum = &x
+			case "exclusiveMaximum":
+				node.exclusiveMaximum = &x
+			}
+
+		case "multipleOf":
+			x, ok := parseDecimal(c.doc.numberText(value))
+			if tpe != tNumber || !ok || x.sign() <= 0 {
+				return fail(keyword, "must be a number greater than zero")
+			}
+			r, ok := x.rat()
+			if !ok {
+				return fail(keyword, "number out of range")
+			}
+			node.multipleOf = &x
+			node.multipleOfRat = r
+
+		case "minLength", "maxLength":
+			x, ok := parseDecimal(c.doc.numberText(value))
+			if tpe != tNumber || !ok || !x.isInteger() || x.sign() < 0 {
+				return fail(keyword, "must be a non-negative integer")
+			}
+			r, ok := x.rat()
+			if !ok || !r.Num().IsInt64() || r.Num().Int64() > int64(^uint(0)>>1) {
+				return fail(keyword, "number out of range")
+			}
+			if keyword == "minLength" {
+				node.minLength = int(r.Num().Int64())
+			} else {
+				node.maxLength = int(r.Num().Int64())
+			}
+
+		case "pattern":
+			if tpe != tString {
+				return fail(keyword, "must be a string")
+			}
+			s, _ := c.doc.stringValue(value)
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return fail(keyword, err.Error())
+			}
+			node.pattern = re
+
+		case "$ref":
+			if tpe != tString {
+				return fail(keyword, "must be a string")
+			}
+			node.ref, _ = c.doc.stringValue(value)
+			c.refs = append(c.refs, refLocation{node: node, location: location})
+
+		case "$anchor":
+			if tpe != tString {
+				return fail(keyword, "must be a string")
+			}
+			name, _ := c.doc.stringValue(value)
+			if _, ok := c.anchors[name]; ok {
+				return fail(keyword, fmt.Sprintf("duplicate anchor %q", name))
+			}
+			c.anchors[name] = index
+		}
+	}
+	return node, nil
+}
+
+// Validate parses the instance data and validates it against the schema.
+// If the data fails to parse, the according error is returned. Otherwise, the
+// result is either nil or ValidationErrors listing all violations.
+func (s *Schema) Validate(data []byte) error {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return err
+	}
+	return s.ValidateDocument(doc)
+}
+
+// ValidateDocument is the document-level equivalent of Validate.
+func (s *Schema) ValidateDocument(doc *Document) error {
+	root := doc.root()
+	if root == 0 {
+		return ErrEmptyDocument
+	}
+	v := schemaValidator{
+		schema: s,
+		doc:    doc,
+		active: map[activeRef]bool{},
+	}
+	if errs := v.validate(s.root, root, "", ""); len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// schemaValidator holds the state while validating an instance.
+type schemaValidator struct {
+	schema *Schema
+	doc    *Document
+	active map[activeRef]bool // references currently being evaluated
+}
+
+// activeRef identifies the evaluation of a reference for a specific value,
+// which is used to detect infinite recursion.
+type activeRef struct {
+	node  *schemaNode
+	index int
+}
+
+// validate validates the instance value at the given index against the
+// schema node and returns the violations found.
+func (v *schemaValidator) validate(node *schemaNode, index int, instanceLoc, keywordLoc string) ValidationErrors {
+	var errs ValidationErrors
+	fail := func(keyword, msg string) {
+		errs = append(errs, ValidationError{
+			InstanceLocation: instanceLoc,
+			KeywordLocation:  appendPointer(keywordLoc, keyword),
+			Message:          msg,
+		})
+	}
+
+	if node.isBool {
+		if !node.boolValue {
+			errs = append(errs, ValidationError{
+				InstanceLocation: instanceLoc,
+				KeywordLocation:  keywordLoc,
+				Message:          "schema doesn't allow any value",
+			})
+		}
+		return errs
+	}
+
+	doc := v.doc
+	tpe := doc.elements[index].tpe
+
+	if len(node.types) != 0 {
+		actual := v.typeName(index)
+		match := false
+		for _, t := range node.types {
+			if t == actual || (t == "number" && actual == "integer") {
+				match = true
+				break
+			}
+		}
+		if !match {
+			if actual == "integer" {
+				actual = "number"
+			}
+			fail("type", fmt.Sprintf("expected %s, found %s", strings.Join(node.types, " or "), actual))
+		}
+	}
+
+	if node.enum != nil {
+		match := false
+		for _, e := range node.enum {
+			if equalValues(v.schema.doc, e, doc, index) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			fail("enum", "value is not one of the enumerated values")
+		}
+	}
+
+	if node.constant != 0 && !equalValues(v.schema.doc, node.constant, doc, index) {
+		fail("const", "value doesn't match the constant")
+	}
+
+	switch tpe {
+	case tNumber:
+		x, _ := parseDecimal(doc.numberText(index))
+		if node.minimum != nil && compareDecimal(x, *node.minimum) < 0 {
+			fail("minimum", fmt.Sprintf("value is less than %s", node.minimum.plain()))
+		}
+		if node.maximum != nil && compareDecimal(x, *node.maximum) > 0 {
+			fail("maximum", fmt.Sprintf("value is greater than %s", node.maximum.plain()))
+		}
+		if node.exclusiveMinimum != nil && compareDecimal(x, *node.exclusiveMinimum) <= 0 {
+			fail("exclusiveMinimum", fmt.Sprintf("value is not greater than %s", node.exclusiveMinimum.plain()))
+		}
+		if node.exclusiveMaximum != nil && compareDecimal(x, *node.exclusiveMaximum) >= 0 {
+			fail("exclusiveMaximum", fmt.Sprintf("value is not less than %s", node.exclusiveMaximum.plain()))
+		}
+		if node.multipleOf != nil {
+			r, ok := x.rat()
+			if !ok {
+				fail("multipleOf", "value is out of range")
+			} else if !r.Quo(r, node.multipleOfRat).IsInt() {
+				fail("multipleOf", fmt.Sprintf("value is not a multiple of %s", node.multipleOf.plain()))
+			}
+		}
+
+	case tString:
+		if node.minLength >= 0 || node.maxLength >= 0 || node.pattern != nil {
+			s, _ := doc.stringValue(index)
+			length := utf8.RuneCountInString(s)
+			if node.minLength >= 0 && length < node.minLength {
+				fail("minLength", fmt.Sprintf("string is shorter than %d characters", node.minLength))
+			}
+			if node.maxLength >= 0 && length > node.maxLength {
+				fail("maxLength", fmt.Sprintf("string is longer than %d characters", node.maxLength))
+			}
+			if node.pattern != nil && !node.pattern.MatchString(s) {
+				fail("pattern", fmt.Sprintf("string doesn't match pattern %q", node.pattern.String()))
+			}
+		}
+
+	case tObjectStart:
+		children := doc.children(index)
+		present := make(map[string]bool, len(children)/2)
+		for i := 0; i+1 < len(children); i += 2 {
+			name, _ := doc.stringValue(children[i])
+			present[name] = true
+			if sub, ok := node.properties[name]; ok {
+				errs = append(errs, v.validate(sub, children[i+1],
+					appendPointer(instanceLoc, name),
+					appendPointer(appendPointer(keywordLoc, "properties"), name))...)
+			}
+		}
+		for _, name := range node.required {
+			if !present[name] {
+				fail("required", fmt.Sprintf("required property %q is missing", name))
+			}
+		}
+
+	case tArrayStart:
+		for i, item := range doc.children(index) {
+			if i < len(node.prefixItems) {
+				errs = append(errs, v.validate(node.prefixItems[i], item,
+					appendPointerIndex(instanceLoc, i),
+					appendPointerIndex(appendPointer(keywordLoc, "prefixItems"), i))...)
+			} else if node.items != nil {
+				errs = append(errs, v.validate(node.items, item,
+					appendPointerIndex(instanceLoc, i),
+					appendPointer(keywordLoc, "items"))...)
+			}
+		}
+	}
+
+	if node.refNode != nil {
+		key := activeRef{node: node, index: index}
+		if v.active[key] {
+			fail("$ref", "infinite recursion")
+		} else {
+			v.active[key] = true
+			errs = append(errs, v.validate(node.refNode, index, instanceLoc, appendPointer(keywordLoc, "$ref"))...)
+			delete(v.active, key)
+		}
+	}
+
+	for i, sub := range node.allOf {
+		errs = append(errs, v.validate(sub, index, instanceLoc, appendPointerIndex(appendPointer(keywordLoc, "allOf"), i))...)
+	}
+
+	if len(node.anyOf) != 0 {
+		match := false
+		for i, sub := range node.anyOf {
+			if len(v.validate(sub, index, instanceLoc, appendPointerIndex(appendPointer(keywordLoc, "anyOf"), i))) == 0 {
+				match = true
+				break
+			}
+		}
+		if !match {
+			fail("anyOf", "value doesn't match any of the schemas")
+		}
+	}
+
+	if len(node.oneOf) != 0 {
+		matches := 0
+		for i, sub := range node.oneOf {
+			if len(v.validate(sub, index, instanceLoc, appendPointerIndex(appendPointer(keywordLoc, "oneOf"), i))) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("oneOf", fmt.Sprintf("value matches %d of the schemas instead of exactly one", matches))
+		}
+	}
+
+	if node.not != nil {
+		if len(v.validate(node.not, index, instanceLoc, appendPointer(keywordLoc, "not"))) == 0 {
+			fail("not", "value must not match the schema")
+		}
+	}
+
+	return errs
+}
+
+// typeName returns the JSON Schema type name of the value at the given index.
+// Numbers without fractional part are reported as "integer".
+func (v *schemaValidator) typeName(index int) string {
+	switch v.doc.elements[index].tpe {
+	case tNull:
+		return "null"
+	case tBool:
+		return "boolean"
+	case tObjectStart:
+		return "object"
+	case tArrayStart:
+		return "array"
+	case tString:
+		return "string"
+	case tNumber:
+		x, _ := parseDecimal(v.doc.numberText(index))
+		if x.isInteger() {
+			return "integer"
+		}
+		return "number"
+	default:
+		return "unknown"
+	}
+}
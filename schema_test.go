@@ -0,0 +1,221 @@
+package main
+
+import (
+	"testing"
+)
+
+type schemaTest struct {
+	schema   []byte
+	instance []byte
+	// expected violations as pairs of instance and keyword location
+	errors [][2]string
+}
+
+func TestSchemaValidate(t *testing.T) {
+	cases := map[string]schemaTest{
+		"true": {
+			schema:   []byte(`true`),
+			instance: []byte(`{"a": [1, 2]}`),
+		},
+		"false": {
+			schema:   []byte(`false`),
+			instance: []byte(`null`),
+			errors:   [][2]string{{"", ""}},
+		},
+		"empty": {
+			schema:   []byte(`{}`),
+			instance: []byte(`"anything"`),
+		},
+		"type 1": {
+			schema:   []byte(`{"type": "string"}`),
+			instance: []byte(`"s"`),
+		},
+		"type 2": {
+			schema:   []byte(`{"type": "string"}`),
+			instance: []byte(`1`),
+			errors:   [][2]string{{"", "/type"}},
+		},
+		"type 3": {
+			schema:   []byte(`{"type": ["string", "null"]}`),
+			instance: []byte(`null`),
+		},
+		"type 4": {
+			schema:   []byte(`{"type": "integer"}`),
+			instance: []byte(`1.0`),
+		},
+		"type 5": {
+			schema:   []byte(`{"type": "integer"}`),
+			instance: []byte(`1.5`),
+			errors:   [][2]string{{"", "/type"}},
+		},
+		"type 6": {
+			schema:   []byte(`{"type": "number"}`),
+			instance: []byte(`15e-1`),
+		},
+		"properties 1": {
+			schema:   []byte(`{"properties": {"a": {"type": "number"}, "b/c": {"type": "string"}}}`),
+			instance: []byte(`{"a": 1, "b/c": 2, "d": 3}`),
+			errors:   [][2]string{{"/b~1c", "/properties/b~1c/type"}},
+		},
+		"required": {
+			schema:   []byte(`{"required": ["a", "b"]}`),
+			instance: []byte(`{"a": 1}`),
+			errors:   [][2]string{{"", "/required"}},
+		},
+		"items": {
+			schema:   []byte(`{"items": {"type": "number"}}`),
+			instance: []byte(`[1, "2", 3, null]`),
+			errors:   [][2]string{{"/1", "/items/type"}, {"/3", "/items/type"}},
+		},
+		"prefixItems": {
+			schema:   []byte(`{"prefixItems": [{"type": "string"}], "items": {"type": "number"}}`),
+			instance: []byte(`["a", 1, "b"]`),
+			errors:   [][2]string{{"/2", "/items/type"}},
+		},
+		"enum 1": {
+			schema:   []byte(`{"enum": [1, "a", {"x": null}]}`),
+			instance: []byte(`{ "x" : null }`),
+		},
+		"enum 2": {
+			schema:   []byte(`{"enum": [1, "a", {"x": null}]}`),
+			instance: []byte(`1.0`),
+		},
+		"enum 3": {
+			schema:   []byte(`{"enum": [1, "a", {"x": null}]}`),
+			instance: []byte(`"b"`),
+			errors:   [][2]string{{"", "/enum"}},
+		},
+		"const": {
+			schema:   []byte(`{"const": [true]}`),
+			instance: []byte(`[false]`),
+			errors:   [][2]string{{"", "/const"}},
+		},
+		"numeric bounds 1": {
+			schema:   []byte(`{"items": {"minimum": 1.5, "exclusiveMaximum": 3}}`),
+			instance: []byte(`[1, 1.5, 3, 2.9999999999999999999999]`),
+			errors:   [][2]string{{"/0", "/items/minimum"}, {"/2", "/items/exclusiveMaximum"}},
+		},
+		"numeric bounds 2": {
+			schema:   []byte(`{"items": {"exclusiveMinimum": -1, "maximum": 1e2}}`),
+			instance: []byte(`[-1, 100, 100.5, 1e100000000]`),
+			errors:   [][2]string{{"/0", "/items/exclusiveMinimum"}, {"/2", "/items/maximum"}, {"/3", "/items/maximum"}},
+		},
+		"multipleOf": {
+			schema:   []byte(`{"items": {"multipleOf": 0.1}}`),
+			instance: []byte(`[0.3, 1, 0.35]`),
+			errors:   [][2]string{{"/2", "/items/multipleOf"}},
+		},
+		"string bounds": {
+			schema:   []byte(`{"items": {"minLength": 2, "maxLength": 3}}`),
+			instance: []byte(`["a", "ab", "äöü", "äöüß"]`),
+			errors:   [][2]string{{"/0", "/items/minLength"}, {"/3", "/items/maxLength"}},
+		},
+		"pattern": {
+			schema:   []byte(`{"items": {"pattern": "^[a-z]+$"}}`),
+			instance: []byte(`["abc", "aBc", 1]`),
+			errors:   [][2]string{{"/1", "/items/pattern"}},
+		},
+		"ref 1": {
+			schema:   []byte(`{"$defs": {"pos": {"type": "integer", "minimum": 1}}, "items": {"$ref": "#/$defs/pos"}}`),
+			instance: []byte(`[1, -1]`),
+			errors:   [][2]string{{"/1", "/items/$ref/minimum"}},
+		},
+		"ref 2": {
+			schema:   []byte(`{"type": "array", "items": {"$ref": "#"}}`),
+			instance: []byte(`[[], [[1]]]`),
+			errors:   [][2]string{{"/1/0/0", "/items/$ref/items/$ref/items/$ref/type"}},
+		},
+		"ref 3": {
+			schema:   []byte(`{"$defs": {"s": {"$anchor": "str", "type": "string"}}, "$ref": "#str"}`),
+			instance: []byte(`1`),
+			errors:   [][2]string{{"", "/$ref/type"}},
+		},
+		"ref 4": {
+			schema:   []byte(`{"$ref": "#"}`),
+			instance: []byte(`1`),
+			errors:   [][2]string{{"", "/$ref/$ref"}},
+		},
+		"allOf": {
+			schema:   []byte(`{"allOf": [{"type": "number"}, {"minimum": 2}]}`),
+			instance: []byte(`1`),
+			errors:   [][2]string{{"", "/allOf/1/minimum"}},
+		},
+		"anyOf": {
+			schema:   []byte(`{"anyOf": [{"type": "string"}, {"minimum": 2}]}`),
+			instance: []byte(`1`),
+			errors:   [][2]string{{"", "/anyOf"}},
+		},
+		"oneOf 1": {
+			schema:   []byte(`{"oneOf": [{"type": "number"}, {"minimum": 2}]}`),
+			instance: []byte(`1`),
+		},
+		"oneOf 2": {
+			schema:   []byte(`{"oneOf": [{"type": "number"}, {"minimum": 2}]}`),
+			instance: []byte(`2`),
+			errors:   [][2]string{{"", "/oneOf"}},
+		},
+		"not": {
+			schema:   []byte(`{"not": {"type": "null"}}`),
+			instance: []byte(`null`),
+			errors:   [][2]string{{"", "/not"}},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			schema, err := CompileSchema(c.schema)
+			if err != nil {
+				t.Fatal("failed to compile schema", err)
+			}
+			err = schema.Validate(c.instance)
+			if len(c.errors) == 0 {
+				if err != nil {
+					t.Error("unexpected failure", err)
+				}
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatal("expected validation errors, received", err)
+			}
+			if len(errs) != len(c.errors) {
+				t.Log("received errors", errs)
+				t.Fatalf("expected %d errors, received %d", len(c.errors), len(errs))
+			}
+			for i, e := range c.errors {
+				if errs[i].InstanceLocation != e[0] || errs[i].KeywordLocation != e[1] {
+					t.Log("expected locations", e)
+					t.Log("received error", errs[i])
+					t.Errorf("error %d differs", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileSchema(t *testing.T) {
+	cases := map[string][]byte{
+		"not a schema":      []byte(`1`),
+		"invalid type":      []byte(`{"type": "float"}`),
+		"invalid required":  []byte(`{"required": "a"}`),
+		"invalid minimum":   []byte(`{"minimum": "1"}`),
+		"invalid minLength": []byte(`{"minLength": 1.5}`),
+		"invalid pattern":   []byte(`{"pattern": "("}`),
+		"remote ref":        []byte(`{"$ref": "http://example.com/schema"}`),
+		"unresolvable ref":  []byte(`{"$ref": "#/$defs/missing"}`),
+		"unknown anchor":    []byte(`{"$ref": "#missing"}`),
+		"invalid subschema": []byte(`{"items": [1]}`),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := CompileSchema(data)
+			if err == nil {
+				t.Fatal("expected error missing")
+			}
+			if _, ok := err.(*SchemaError); !ok {
+				t.Error("unexpected error type", err)
+			}
+		})
+	}
+}
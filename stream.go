@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// StreamParser parses a document that arrives in chunks, e.g. over a chunked
+// transport, so that processing can start before the input is complete. The
+// input must be UTF-8 without byte order mark.
+//
+// If the input is truncated, Finish fails with ErrUnexpectedEOF. The elements
+// parsed so far and the open arrays and objects remain available, and parsing
+// resumes when more input is written.
+type StreamParser struct {
+	p   *incrementalParser
+	cur int // offset of the first token that wasn't processed yet
+}
+
+// NewStreamParser returns a parser for a document configured by the options.
+func NewStreamParser(opts ParseOptions) *StreamParser {
+	return &StreamParser{p: newIncrementalParser(nil, opts, true)}
+}
+
+// Write appends the data to the input and parses it as far as possible. It
+// fails if the input can't be the start of a valid document. Tokens at the
+// end of the input that may continue, like numbers, are held back until more
+// input arrives or Finish is called.
+func (s *StreamParser) Write(data []byte) (int, error) {
+	if err := s.err(); err != nil {
+		return 0, err
+	}
+	if max := s.p.opts.MaxInputSize; max > 0 && len(s.p.data)+len(data) > max {
+		s.p.report(max, ErrInputTooLarge)
+		return 0, s.err()
+	}
+	s.p.data = append(s.p.data, data...)
+	s.cur = s.parse(s.p, false)
+	return len(data), s.err()
+}
+
+// Finish parses the rest of the input and returns the document. It fails with
+// ErrUnexpectedEOF if the input is truncated, in which case more input can be
+// written before calling Finish again.
+func (s *StreamParser) Finish() (*Document, error) {
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	// the held back tokens may still continue, so keep the state unchanged
+	p := s.p.clone()
+	s.parse(p, true)
+	if len(p.errs) > 0 {
+		return nil, p.errs[0]
+	}
+	if p.context != 0 {
+		return nil, &ParseError{Offset: len(p.data), Err: ErrUnexpectedEOF}
+	}
+	elements, err := applyDuplicateKeyPolicy(p.data, p.res, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	return newDocument(p.data, elements, p.opts.Syntax), nil
+}
+
+// Elements returns the elements of the syntax tree parsed so far.
+func (s *StreamParser) Elements() []JSONElement {
+	return s.p.res
+}
+
+// Open returns the indices of the arrays and objects among the elements that
+// are still open, innermost last.
+func (s *StreamParser) Open() []int {
+	return s.p.open()
+}
+
+// err returns the error that stopped parsing, if any.
+func (s *StreamParser) err() error {
+	if len(s.p.errs) > 0 {
+		return s.p.errs[0]
+	}
+	return nil
+}
+
+// parse processes the tokens of the input starting at the current offset. It
+// returns the offset of the first token that wasn't processed. Unless final,
+// tokens that may continue beyond the end of the input are held back.
+func (s *StreamParser) parse(p *incrementalParser, final bool) int {
+	data := p.data
+	cur := s.cur
+	for cur != len(data) && !p.stopped {
+		if !final && !utf8.FullRune(data[cur:]) {
+			// incomplete character
+			break
+		}
+		tpe, size, err := nextToken(data, cur, p.opts)
+		if err != nil {
+			if !final && errors.Is(err, ErrUnexpectedEOF) {
+				break
+			}
+			p.tokenError(cur, err)
+			break
+		}
+		if !final && mayContinue(data, JSONElement{tpe: tpe, offset: cur}, size) {
+			break
+		}
+		if tpe != tNone {
+			p.token(JSONElement{tpe: tpe, offset: cur})
+		}
+		cur += size
+	}
+	return cur
+}
+
+// mayContinue tells whether the token of the given size may continue with
+// more input, because it ends at the end of the data.
+func mayContinue(data []byte, elem JSONElement, size int) bool {
+	end := elem.offset + size
+	if end != len(data) && utf8.FullRune(data[end:]) {
+		return false
+	}
+	switch elem.tpe {
+	case tNumber, tBool, tNull:
+		return true
+	case tString:
+		// identifiers of JSON5
+		return isIdentifierToken(data, elem)
+	case tNone, tComment:
+		// single-line comments, which are skipped like whitespace in JSON5
+		return size > 1 && data[elem.offset] == '/' && data[elem.offset+1] == '/'
+	default:
+		return false
+	}
+}
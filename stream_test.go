@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// parseChunks parses the data with a stream parser, writing it in chunks of
+// the given sizes. The rest of the data is written at once.
+func parseChunks(data []byte, opts ParseOptions, sizes ...int) (*Document, error) {
+	s := NewStreamParser(opts)
+	for _, size := range sizes {
+		if _, err := s.Write(data[:size]); err != nil {
+			return nil, err
+		}
+		data = data[size:]
+	}
+	if _, err := s.Write(data); err != nil {
+		return nil, err
+	}
+	return s.Finish()
+}
+
+func TestStreamParser(t *testing.T) {
+	for name, c := range parseJSONCases {
+		t.Run(name, func(t *testing.T) {
+			expected, expectedErr := parseJSON(c.data)
+			for i := 0; i <= len(c.data); i++ {
+				doc, err := parseChunks(c.data, ParseOptions{}, i)
+				if (err == nil) != (expectedErr == nil) {
+					t.Fatalf("split at %d: expected error %v, received %v", i, expectedErr, err)
+				}
+				if errors.Is(err, ErrUnexpectedEOF) && !errors.Is(expectedErr, ErrUnexpectedEOF) {
+					t.Fatalf("split at %d: expected error %v, received %v", i, expectedErr, err)
+				}
+				if err == nil && fmt.Sprint(doc.elements) != fmt.Sprint(expected) {
+					t.Fatalf("split at %d: expected elements %v, received %v", i, expected, doc.elements)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamParserSyntax(t *testing.T) {
+	cases := map[string]struct {
+		data string
+		opts ParseOptions
+	}{
+		"json5":       {data: "{a: 'b', // c\n d: [+.5, 0x1F, -Infinity, NaN,], tru\\u0065: 1}", opts: ParseOptions{Syntax: SyntaxJSON5}},
+		"jsonc":       {data: "[1, // c\n 2 /* d */, 3,]", opts: ParseOptions{Syntax: SyntaxJSONC}},
+		"multibyte":   {data: "[\"äöü\", \"😀\"]"},
+		"json5 space": {data: "[1,   2]", opts: ParseOptions{Syntax: SyntaxJSON5}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			expected, err := ParseDocumentOptions([]byte(c.data), c.opts)
+			if err != nil {
+				t.Fatal("invalid test input", err)
+			}
+			data := []byte(c.data)
+			sizes := []int{}
+			for range data {
+				// write byte by byte
+				sizes = append(sizes, 1)
+			}
+			doc, err := parseChunks(data, c.opts, sizes...)
+			if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+			if fmt.Sprint(doc.elements) != fmt.Sprint(expected.elements) {
+				t.Errorf("expected elements %v, received %v", expected.elements, doc.elements)
+			}
+		})
+	}
+}
+
+func TestStreamParserResume(t *testing.T) {
+	s := NewStreamParser(ParseOptions{})
+	if _, err := s.Write([]byte(`{"a": [1, 2`)); err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	if _, err := s.Finish(); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Fatalf("expected error %v, received %v", ErrUnexpectedEOF, err)
+	}
+	// the number may continue, so it isn't part of the elements yet
+	if len(s.Elements()) != 7 {
+		t.Errorf("expected 7 elements, received %v", s.Elements())
+	}
+	if fmt.Sprint(s.Open()) != "[1 4]" {
+		t.Errorf("expected open elements [1 4], received %v", s.Open())
+	}
+
+	if _, err := s.Write([]byte(`3], "b": tr`)); err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	if _, err := s.Finish(); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Fatalf("expected error %v, received %v", ErrUnexpectedEOF, err)
+	}
+	if fmt.Sprint(s.Open()) != "[1]" {
+		t.Errorf("expected open elements [1], received %v", s.Open())
+	}
+
+	if _, err := s.Write([]byte(`ue}`)); err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	doc, err := s.Finish()
+	if err != nil {
+		t.Fatal("unexpected failure", err)
+	}
+	expected, _ := ParseDocument([]byte(`{"a": [1, 23], "b": true}`))
+	if fmt.Sprint(doc.elements) != fmt.Sprint(expected.elements) {
+		t.Errorf("expected elements %v, received %v", expected.elements, doc.elements)
+	}
+
+	s.Write([]byte(` 1`))
+	if _, err := s.Finish(); !errors.Is(err, ErrInvalidStructure) {
+		t.Errorf("expected error %v for second value, received %v", ErrInvalidStructure, err)
+	}
+}
+
+func TestParseJSONUnexpectedEOF(t *testing.T) {
+	elements, err := parseJSON([]byte(`{"k": [1, {}`))
+	if err != ErrUnexpectedEOF {
+		t.Fatalf("expected error %v, received %v", ErrUnexpectedEOF, err)
+	}
+	if len(elements) != 9 {
+		t.Errorf("expected the 9 elements parsed so far, received %v", elements)
+	}
+}
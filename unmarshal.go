@@ -0,0 +1,604 @@
+package main
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// InvalidUnmarshalError signals that the target passed to Unmarshal is not a
+// non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "unmarshal: target must be a non-nil pointer, not nil"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "unmarshal: target must be a non-nil pointer, not " + e.Type.String()
+	}
+	return "unmarshal: target must be a non-nil pointer, not nil " + e.Type.String()
+}
+
+// UnmarshalTypeError signals that a JSON value can't be stored in a Go value.
+type UnmarshalTypeError struct {
+	Value   string       // description of the JSON value, like "string" or "number 1e999"
+	Type    reflect.Type // type of the Go value it could not be assigned to
+	Pointer string       // JSON Pointer to the value
+	Offset  int          // offset of the value within the input data
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return fmt.Sprintf("unmarshal: cannot store %s at %q in Go value of type %s", e.Value, e.Pointer, e.Type)
+}
+
+var (
+	numberType          = reflect.TypeOf(json.Number(""))
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshal parses the JSON data and stores the result in the value pointed
+// to by v. It follows the semantics of encoding/json.Unmarshal:
+//
+//   - Objects are stored in structs, matching member names to field names or
+//     the names from "json" struct tags, preferring exact over
+//     case-insensitive matches. Fields of embedded structs are promoted.
+//     Unknown members are ignored. The tag option "string" reads scalar
+//     values from inside a JSON string.
+//   - Objects are stored in maps with string, integer or
+//     encoding.TextUnmarshaler keys.
+//   - Arrays are stored in slices and arrays, strings are decoded as base64
+//     for byte slices.
+//   - Values stored in an empty interface become nil, bool, float64, string,
+//     []interface{} or map[string]interface{}.
+//   - Types implementing json.Unmarshaler receive the raw JSON value, types
+//     implementing encoding.TextUnmarshaler receive the content of strings.
+//   - Null sets pointers, interfaces, maps and slices to nil and leaves
+//     other values unchanged.
+//   - If a value doesn't fit the Go type, decoding continues and the first
+//     such error is returned at the end.
+//
+// Deviations from encoding/json are:
+//
+//   - UnmarshalTypeError contains the JSON Pointer to the offending value
+//     instead of the struct and field name.
+//   - Invalid UTF-8 in strings and escapes of unpaired surrogates are
+//     rejected. To replace them with U+FFFD like encoding/json, parse the
+//     document with ReplaceInvalidUTF8 and AllowLoneSurrogates and use
+//     UnmarshalDocument.
+//   - When reusing the backing array of a slice, the elements are zeroed
+//     before decoding into them.
+//   - There are no options like DisallowUnknownFields or UseNumber, though
+//     json.Number is supported as target type.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return err
+	}
+	return UnmarshalDocument(doc, v)
+}
+
+// UnmarshalDocument is the document-level equivalent of Unmarshal.
+func UnmarshalDocument(doc *Document, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	root := doc.root()
+	if root == 0 {
+		return ErrEmptyDocument
+	}
+
+	d := decoder{doc: doc}
+	if err := d.value(root, nil, rv); err != nil {
+		return err
+	}
+	return d.typeErr
+}
+
+// pointerPath is the JSON Pointer to a value while decoding. It is only turned
+// into a string for errors, so that decoding deeply nested values doesn't
+// take quadratic time.
+type pointerPath struct {
+	parent *pointerPath
+	token  string // escaped reference token
+}
+
+// key returns the path of the object member of the given name.
+func (p *pointerPath) key(name string) *pointerPath {
+	return &pointerPath{parent: p, token: pointerEscaper.Replace(name)}
+}
+
+// index returns the path of the array element at the given index.
+func (p *pointerPath) index(i int) *pointerPath {
+	return &pointerPath{parent: p, token: strconv.Itoa(i)}
+}
+
+// String returns the JSON Pointer, which is empty for the root.
+func (p *pointerPath) String() string {
+	tokens := []string{}
+	for ; p != nil; p = p.parent {
+		tokens = append(tokens, p.token)
+	}
+	var b strings.Builder
+	for i := len(tokens) - 1; i >= 0; i-- {
+		b.WriteString("/")
+		b.WriteString(tokens[i])
+	}
+	return b.String()
+}
+
+// decoder holds the state while storing a document in a Go value.
+type decoder struct {
+	doc     *Document
+	typeErr error // first type mismatch
+}
+
+// mismatch records a type mismatch, only the first one is kept.
+func (d *decoder) mismatch(index int, path *pointerPath, what string, t reflect.Type) {
+	if d.typeErr == nil {
+		d.typeErr = &UnmarshalTypeError{
+			Value:   what,
+			Type:    t,
+			Pointer: path.String(),
+			Offset:  d.doc.elements[index].offset,
+		}
+	}
+}
+
+// value stores the JSON value at the given index in v. Type mismatches are
+// recorded, other errors abort decoding.
+func (d *decoder) value(index int, path *pointerPath, v reflect.Value) error {
+	tpe := d.doc.elements[index].tpe
+	u, tu, v := indirect(v, tpe == tNull)
+	if u != nil {
+		return u.UnmarshalJSON(d.doc.text(index))
+	}
+	if tu != nil {
+		switch tpe {
+		case tString:
+			s, err := d.doc.stringValue(index)
+			if err != nil {
+				return err
+			}
+			return tu.UnmarshalText([]byte(s))
+		case tNull:
+			return nil
+		default:
+			d.mismatch(index, path, d.describe(index), reflect.TypeOf(tu))
+			return nil
+		}
+	}
+
+	switch tpe {
+	case tNull:
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	case tBool:
+		return d.boolValue(index, path, v)
+	case tNumber:
+		return d.numberValue(index, path, string(d.doc.numberText(index)), v)
+	case tString:
+		return d.stringValue(index, path, v)
+	case tArrayStart:
+		return d.array(index, path, v)
+	case tObjectStart:
+		return d.object(index, path, v)
+	default:
+		return ErrInvalidStructure
+	}
+}
+
+// describe returns a description of the JSON value for error messages.
+func (d *decoder) describe(index int) string {
+	switch d.doc.elements[index].tpe {
+	case tNull:
+		return "null"
+	case tBool:
+		return "bool"
+	case tNumber:
+		return "number " + string(d.doc.text(index))
+	case tString:
+		return "string"
+	case tArrayStart:
+		return "array"
+	case tObjectStart:
+		return "object"
+	default:
+		return "value"
+	}
+}
+
+func (d *decoder) boolValue(index int, path *pointerPath, v reflect.Value) error {
+	b := d.doc.boolValue(index)
+	switch {
+	case v.Kind() == reflect.Bool:
+		v.SetBool(b)
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		v.Set(reflect.ValueOf(b))
+	default:
+		d.mismatch(index, path, "bool", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) numberValue(index int, path *pointerPath, s string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			d.mismatch(index, path, "number "+s, v.Type())
+			return nil
+		}
+		v.Set(reflect.ValueOf(f))
+		return nil
+	case reflect.String:
+		if v.Type() != numberType {
+			break
+		}
+		v.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			d.mismatch(index, path, "number "+s, v.Type())
+			return nil
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			d.mismatch(index, path, "number "+s, v.Type())
+			return nil
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil || v.OverflowFloat(f) {
+			d.mismatch(index, path, "number "+s, v.Type())
+			return nil
+		}
+		v.SetFloat(f)
+		return nil
+	}
+	d.mismatch(index, path, "number", v.Type())
+	return nil
+}
+
+func (d *decoder) stringValue(index int, path *pointerPath, v reflect.Value) error {
+	s, err := d.doc.stringValue(index)
+	if err != nil {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if v.Type() == numberType {
+			// only valid numbers can be stored in json.Number
+			if !isNumber(s) {
+				d.mismatch(index, path, "string", v.Type())
+				return nil
+			}
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			d.mismatch(index, path, "string", v.Type())
+			return nil
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.mismatch(index, path, "string", v.Type())
+			return nil
+		}
+		v.Set(reflect.ValueOf(s))
+	default:
+		d.mismatch(index, path, "string", v.Type())
+	}
+	return nil
+}
+
+// isNumber tells whether the string is exactly one valid JSON number.
+func isNumber(s string) bool {
+	size, err := findEndOfNumber([]byte(s), 0, len(s), SyntaxJSON)
+	return err == nil && size == len(s)
+}
+
+func (d *decoder) array(index int, path *pointerPath, v reflect.Value) error {
+	children := d.doc.children(index)
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		res := make([]interface{}, len(children))
+		for i, child := range children {
+			if err := d.value(child, path.index(i), reflect.ValueOf(&res[i]).Elem()); err != nil {
+				return err
+			}
+		}
+		v.Set(reflect.ValueOf(res))
+		return nil
+	case reflect.Slice:
+		n := len(children)
+		if v.IsNil() || v.Cap() < n {
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+		} else {
+			v.SetLen(n)
+			zero := reflect.Zero(v.Type().Elem())
+			for i := 0; i < n; i++ {
+				v.Index(i).Set(zero)
+			}
+		}
+		for i, child := range children {
+			if err := d.value(child, path.index(i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		for i, child := range children {
+			if i >= v.Len() {
+				// surplus elements are discarded
+				break
+			}
+			if err := d.value(child, path.index(i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+		// missing elements are zeroed
+		zero := reflect.Zero(v.Type().Elem())
+		for i := len(children); i < v.Len(); i++ {
+			v.Index(i).Set(zero)
+		}
+		return nil
+	}
+	d.mismatch(index, path, "array", v.Type())
+	return nil
+}
+
+func (d *decoder) object(index int, path *pointerPath, v reflect.Value) error {
+	children := d.doc.children(index)
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		res := make(map[string]interface{}, len(children)/2)
+		for i := 0; i+1 < len(children); i += 2 {
+			key, err := d.doc.stringValue(children[i])
+			if err != nil {
+				return err
+			}
+			var elem interface{}
+			if err := d.value(children[i+1], path.key(key), reflect.ValueOf(&elem).Elem()); err != nil {
+				return err
+			}
+			res[key] = elem
+		}
+		v.Set(reflect.ValueOf(res))
+		return nil
+
+	case reflect.Map:
+		t := v.Type()
+		kt := t.Key()
+		switch kt.Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+				d.mismatch(index, path, "object", t)
+				return nil
+			}
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(children)/2))
+		}
+		for i := 0; i+1 < len(children); i += 2 {
+			key, err := d.doc.stringValue(children[i])
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := d.value(children[i+1], path.key(key), elem); err != nil {
+				return err
+			}
+			kv, ok, err := mapKey(kt, key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				d.mismatch(children[i], path.key(key), "number "+key, kt)
+				continue
+			}
+			v.SetMapIndex(kv, elem)
+		}
+		return nil
+
+	case reflect.Struct:
+		fields := cachedTypeFields(v.Type())
+		for i := 0; i+1 < len(children); i += 2 {
+			key, err := d.doc.stringValue(children[i])
+			if err != nil {
+				return err
+			}
+			f := fields.lookup(key)
+			if f == nil {
+				// unknown members are ignored
+				continue
+			}
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || !fv.CanSet() {
+				// embedded pointer to unexported struct type
+				return fmt.Errorf("unmarshal: cannot set embedded pointer to unexported struct: %v", v.Type().FieldByIndex(f.index[:1]).Type)
+			}
+			loc := path.key(key)
+			if f.asString {
+				if err := d.quoted(children[i+1], loc, fv); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.value(children[i+1], loc, fv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	d.mismatch(index, path, "object", v.Type())
+	return nil
+}
+
+// quoted stores a value that is wrapped in a JSON string, as selected by the
+// "string" option in a struct tag.
+func (d *decoder) quoted(index int, path *pointerPath, v reflect.Value) error {
+	switch d.doc.elements[index].tpe {
+	case tNull:
+		return d.value(index, path, v)
+	case tString:
+	default:
+		return fmt.Errorf("unmarshal: invalid use of ,string struct tag, trying to unmarshal unquoted value at %q into %v", path.String(), v.Type())
+	}
+
+	s, err := d.doc.stringValue(index)
+	if err != nil {
+		return err
+	}
+	inner, err := ParseDocument([]byte(s))
+	if err != nil || inner.root() == 0 || len(inner.children(0)) != 1 {
+		return fmt.Errorf("unmarshal: invalid use of ,string struct tag, trying to unmarshal %q at %q into %v", s, path.String(), v.Type())
+	}
+	root := inner.root()
+	switch inner.elements[root].tpe {
+	case tNull, tBool, tNumber, tString:
+	default:
+		return fmt.Errorf("unmarshal: invalid use of ,string struct tag, trying to unmarshal %q at %q into %v", s, path.String(), v.Type())
+	}
+
+	// decode the inner value, but report errors against the outer document
+	sub := decoder{doc: inner}
+	if err := sub.value(root, path, v); err != nil {
+		return err
+	}
+	if sub.typeErr != nil && d.typeErr == nil {
+		e := sub.typeErr.(*UnmarshalTypeError)
+		e.Offset = d.doc.elements[index].offset
+		d.typeErr = e
+	}
+	return nil
+}
+
+// mapKey converts an object member name to a map key of the given type. The
+// bool result is false if the name doesn't fit the type.
+func mapKey(kt reflect.Type, key string) (reflect.Value, bool, error) {
+	if reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+		kv := reflect.New(kt)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return reflect.Value{}, false, err
+		}
+		return kv.Elem(), true, nil
+	}
+	switch kt.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(key).Convert(kt), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || reflect.Zero(kt).OverflowInt(n) {
+			return reflect.Value{}, false, nil
+		}
+		return reflect.ValueOf(n).Convert(kt), true, nil
+	default:
+		n, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || reflect.Zero(kt).OverflowUint(n) {
+			return reflect.Value{}, false, nil
+		}
+		return reflect.ValueOf(n).Convert(kt), true, nil
+	}
+}
+
+// fieldByIndex returns the nested field, allocating embedded pointers on the
+// way. It fails if such a pointer can't be set because it is unexported.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// indirect walks down pointers, allocating them as necessary, until it finds
+// a non-pointer value. If on the way it encounters a json.Unmarshaler or an
+// encoding.TextUnmarshaler, it stops and returns that. When decoding null,
+// it stops at the last pointer, so that it can be set to nil.
+func indirect(v reflect.Value, decodingNull bool) (json.Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	// Start with the address of named types, so that methods with pointer
+	// receivers are found.
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		v = v.Addr()
+	}
+	for {
+		// Use the value stored in an interface if it is a non-nil pointer,
+		// otherwise the interface itself is replaced.
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			e := v.Elem()
+			if e.Kind() == reflect.Ptr && !e.IsNil() && (!decodingNull || e.Elem().Kind() == reflect.Ptr) {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			break
+		}
+		if decodingNull && v.CanSet() {
+			break
+		}
+		// prevent infinite loops with values pointing to themselves
+		if v.Elem().Kind() == reflect.Interface && v.Elem().Elem() == v {
+			v = v.Elem()
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().Implements(unmarshalerType) {
+			return v.Interface().(json.Unmarshaler), nil, reflect.Value{}
+		}
+		if !decodingNull && v.Type().Implements(textUnmarshalerType) {
+			return nil, v.Interface().(encoding.TextUnmarshaler), reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return nil, nil, v
+}
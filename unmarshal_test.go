@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type unmarshalTest struct {
+	data   string
+	target func() interface{} // creates a pointer to the target value
+	value  interface{}        // expected target value
+	// whether an UnmarshalTypeError is expected and the JSON Pointer in it
+	mismatch bool
+	pointer  string
+}
+
+type EmbeddedInner struct {
+	B int `json:"b"`
+}
+
+type testEmbedded struct {
+	E string
+	B int `json:"b"` // shadowed by testOuter.B
+}
+
+type testOuter struct {
+	testEmbedded
+	*EmbeddedInner `json:"inner"`
+	A              string            `json:"a"`
+	B              int               `json:"b,omitempty"`
+	N              int64             `json:"n,string"`
+	F              bool              `json:",string"`
+	Skip           int               `json:"-"`
+	P              *float64          `json:"p"`
+	M              map[int]string    `json:"m"`
+	S              []EmbeddedInner   `json:"s"`
+	Raw            json.RawMessage   `json:"raw"`
+	Any            interface{}       `json:"any"`
+	Text           testText          `json:"text"`
+	TextMap        map[testText]bool `json:"textmap"`
+	unexported     int
+}
+
+// testText is a type implementing encoding.TextUnmarshaler.
+type testText struct {
+	s string
+}
+
+func (t *testText) UnmarshalText(text []byte) error {
+	t.s = strings.ToUpper(string(text))
+	return nil
+}
+
+func newFloat(f float64) *float64 {
+	return &f
+}
+
+func TestUnmarshal(t *testing.T) {
+	cases := map[string]unmarshalTest{
+		"bool": {
+			data:   `true`,
+			target: func() interface{} { return new(bool) },
+			value:  true,
+		},
+		"int": {
+			data:   `-12`,
+			target: func() interface{} { return new(int) },
+			value:  -12,
+		},
+		"uint8": {
+			data:   `255`,
+			target: func() interface{} { return new(uint8) },
+			value:  uint8(255),
+		},
+		"float": {
+			data:   `1.5e2`,
+			target: func() interface{} { return new(float32) },
+			value:  float32(150),
+		},
+		"string": {
+			data:   `"a\nbä😀"`,
+			target: func() interface{} { return new(string) },
+			value:  "a\nbä😀",
+		},
+		"bytes": {
+			data:   `"aGVsbG8="`,
+			target: func() interface{} { return new([]byte) },
+			value:  []byte("hello"),
+		},
+		"number": {
+			data:   `12345678901234567890.5`,
+			target: func() interface{} { return new(json.Number) },
+			value:  json.Number("12345678901234567890.5"),
+		},
+		"pointer": {
+			data:   `1.5`,
+			target: func() interface{} { return new(*float64) },
+			value:  newFloat(1.5),
+		},
+		"null pointer": {
+			data: `null`,
+			target: func() interface{} {
+				p := newFloat(1)
+				return &p
+			},
+			value: (*float64)(nil),
+		},
+		"null int": {
+			data: `null`,
+			target: func() interface{} {
+				i := 42
+				return &i
+			},
+			value: 42,
+		},
+		"interface": {
+			data:   `{"a": [1, "x", true, null, {}]}`,
+			target: func() interface{} { return new(interface{}) },
+			value:  map[string]interface{}{"a": []interface{}{1.0, "x", true, nil, map[string]interface{}{}}},
+		},
+		"slice": {
+			data:   `[1, 2, 3]`,
+			target: func() interface{} { return &[]int{9, 9, 9, 9} },
+			value:  []int{1, 2, 3},
+		},
+		"empty slice": {
+			data:   `[]`,
+			target: func() interface{} { return new([]int) },
+			value:  []int{},
+		},
+		"array": {
+			data:   `[1, 2, 3]`,
+			target: func() interface{} { return &[2]int{} },
+			value:  [2]int{1, 2},
+		},
+		"short array": {
+			data:   `[1]`,
+			target: func() interface{} { return &[3]int{7, 8, 9} },
+			value:  [3]int{1, 0, 0},
+		},
+		"map": {
+			data: `{"a": 1, "b": 2}`,
+			target: func() interface{} {
+				return &map[string]int{"c": 3}
+			},
+			value: map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+		"struct": {
+			data: `{"a": "x", "E": "e", "b": 2, "inner": {"b": 3}, "n": "42", "F": "true", "Skip": 1,
+				"p": 0.5, "m": {"1": "one", "-2": "minus two"}, "s": [{"b": 4}], "raw": [ 1, 2 ],
+				"any": {"k": "v"}, "text": "abc", "textmap": {"x": true}, "unexported": 1, "unknown": 1}`,
+			target: func() interface{} { return new(testOuter) },
+			value: testOuter{
+				testEmbedded:  testEmbedded{E: "e"},
+				EmbeddedInner: &EmbeddedInner{B: 3},
+				A:             "x",
+				B:             2,
+				N:             42,
+				F:             true,
+				P:             newFloat(0.5),
+				M:             map[int]string{1: "one", -2: "minus two"},
+				S:             []EmbeddedInner{{B: 4}},
+				Raw:           json.RawMessage(`[ 1, 2 ]`),
+				Any:           map[string]interface{}{"k": "v"},
+				Text:          testText{s: "ABC"},
+				TextMap:       map[testText]bool{{s: "X"}: true},
+			},
+		},
+		"case-insensitive": {
+			data:   `{"A": "x", "e": "y"}`,
+			target: func() interface{} { return new(testOuter) },
+			value:  testOuter{A: "x", testEmbedded: testEmbedded{E: "y"}},
+		},
+		"mismatch 1": {
+			data:     `"x"`,
+			target:   func() interface{} { return new(int) },
+			value:    0,
+			mismatch: true,
+		},
+		"mismatch 2": {
+			data:     `{"s": [{"b": 1}, {"b": "x"}], "a": "y"}`,
+			target:   func() interface{} { return new(testOuter) },
+			value:    testOuter{S: []EmbeddedInner{{B: 1}, {}}, A: "y"},
+			mismatch: true,
+			pointer:  "/s/1/b",
+		},
+		"mismatch 3": {
+			data:     `[1, 256]`,
+			target:   func() interface{} { return new([]uint8) },
+			value:    []uint8{1, 0},
+			mismatch: true,
+			pointer:  "/1",
+		},
+		"mismatch 4": {
+			data:     `{"m": {"x": "y"}}`,
+			target:   func() interface{} { return new(testOuter) },
+			value:    testOuter{M: map[int]string{}},
+			mismatch: true,
+			pointer:  "/m/x",
+		},
+		"mismatch escaped": {
+			data:     `{"a/b": {"c~d": [true, "x"]}}`,
+			target:   func() interface{} { return new(map[string]map[string][]bool) },
+			value:    map[string]map[string][]bool{"a/b": {"c~d": {true, false}}},
+			mismatch: true,
+			pointer:  "/a~1b/c~0d/1",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			target := c.target()
+			err := Unmarshal([]byte(c.data), target)
+			if c.mismatch {
+				e, ok := err.(*UnmarshalTypeError)
+				if !ok {
+					t.Fatal("expected type error, received", err)
+				}
+				if e.Pointer != c.pointer {
+					t.Errorf("expected pointer %q, received %q", c.pointer, e.Pointer)
+				}
+			} else if err != nil {
+				t.Fatal("unexpected failure", err)
+			}
+
+			value := reflect.ValueOf(target).Elem().Interface()
+			if !reflect.DeepEqual(value, c.value) {
+				t.Logf("expected value %#v", c.value)
+				t.Logf("received value %#v", value)
+				t.Error("wrong value")
+			}
+		})
+	}
+}
+
+// testNested is a type for arrays of any depth.
+type testNested []testNested
+
+func TestUnmarshalDeep(t *testing.T) {
+	depth := 5000
+	data := strings.Repeat(`[`, depth) + `"x"` + strings.Repeat(`]`, depth)
+	var v testNested
+	err := Unmarshal([]byte(data), &v)
+	e, ok := err.(*UnmarshalTypeError)
+	if !ok {
+		t.Fatal("expected UnmarshalTypeError, received", err)
+	}
+	if expected := strings.Repeat("/0", depth); e.Pointer != expected {
+		t.Errorf("expected pointer of %d bytes, received %d bytes", len(expected), len(e.Pointer))
+	}
+	if e.Offset != depth {
+		t.Errorf("expected offset %d, received %d", depth, e.Offset)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	var i int
+	if _, ok := Unmarshal([]byte(`1`), i).(*InvalidUnmarshalError); !ok {
+		t.Error("expected error for non-pointer target")
+	}
+	if _, ok := Unmarshal([]byte(`1`), (*int)(nil)).(*InvalidUnmarshalError); !ok {
+		t.Error("expected error for nil target")
+	}
+	if err := Unmarshal([]byte(`[1,]`), &i); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+	if err := Unmarshal([]byte(``), &i); err != ErrEmptyDocument {
+		t.Error("expected error for empty document")
+	}
+	var s struct {
+		N int `json:",string"`
+	}
+	if err := Unmarshal([]byte(`{"N": 1}`), &s); err == nil {
+		t.Error("expected error for unquoted value with string option")
+	}
+}